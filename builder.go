@@ -1,14 +1,24 @@
 package validator
 
 import (
-	"github.com/weilence/schema-validator/schema"
+	"errors"
+	"fmt"
+
 	"github.com/weilence/schema-validator/rule"
+	"github.com/weilence/schema-validator/schema"
 )
 
 // SchemaBuilder provides a unified fluent API for building different schema types
 type SchemaBuilder struct {
 	schema   schema.Schema
 	registry *rule.Registry
+	errs     rule.BuildErrors
+
+	// conditional is the last conditional added by When, completed by Then and Else
+	conditional *schema.Conditional
+
+	// groups limits the validators added next to validation groups
+	groups []string
 }
 
 // Field creates a new field schema builder
@@ -21,17 +31,46 @@ func Array(elementSchema schema.Schema) *SchemaBuilder {
 	return &SchemaBuilder{schema: schema.NewArray(elementSchema), registry: rule.DefaultRegistry()}
 }
 
+// Map creates a new map schema builder
+// A nil key or value schema skips validation of keys or values
+func Map(keySchema, valueSchema schema.Schema) *SchemaBuilder {
+	return &SchemaBuilder{schema: schema.NewMap(keySchema, valueSchema), registry: rule.DefaultRegistry()}
+}
+
 // Object creates a new object schema builder
 func Object() *SchemaBuilder {
 	return &SchemaBuilder{schema: schema.NewObject(), registry: rule.DefaultRegistry()}
 }
 
+// Union creates a discriminated union schema builder, selecting variants by the value at the discriminator path
+func Union(discriminator string) *SchemaBuilder {
+	return &SchemaBuilder{schema: schema.NewUnion(discriminator), registry: rule.DefaultRegistry()}
+}
+
 // Registry sets a custom registry for the builder
 func (b *SchemaBuilder) Registry(r *rule.Registry) *SchemaBuilder {
 	b.registry = r
 	return b
 }
 
+// Groups limits the validators added after it to validation groups, such as
+//
+//	Field().Groups("update").Required().Groups().AddValidator("min", 1)
+//
+// where required only runs in the update group and min in every group
+func (b *SchemaBuilder) Groups(groups ...string) *SchemaBuilder {
+	b.groups = groups
+	return b
+}
+
+// InheritGroup makes group inherit the rules of parents in an object schema and everything below it
+func (b *SchemaBuilder) InheritGroup(group string, parents ...string) *SchemaBuilder {
+	if os, ok := b.schema.(*schema.ObjectSchema); ok {
+		os.InheritGroup(group, parents...)
+	}
+	return b
+}
+
 // Required marks the field as required
 func (b *SchemaBuilder) Required() *SchemaBuilder {
 	b.AddValidator("required")
@@ -45,12 +84,78 @@ func (b *SchemaBuilder) Optional() *SchemaBuilder {
 }
 
 // AddValidator adds a custom validator to the underlying schema
+// Unknown validators and mismatched params are collected and reported by BuildE
 func (b *SchemaBuilder) AddValidator(name string, params ...any) *SchemaBuilder {
-	v := b.registry.NewValidator(name, params...)
-	b.schema.AddValidator(v)
+	v, err := b.registry.NewValidatorE(name, params...)
+	if err != nil {
+		var buildErr rule.BuildError
+		if !errors.As(err, &buildErr) {
+			buildErr = rule.BuildError{Rule: name, Err: err}
+		}
+		b.errs = append(b.errs, buildErr)
+		return b
+	}
+
+	b.schema.AddValidator(rule.InGroups(v, b.groups...))
+	return b
+}
+
+// AnyOf adds a validator passing when any of the branches passes, each branch being the
+// rules added to a Field() builder. A failure is reported as a single anyOf error
+// A branch without rules always passes
+func (b *SchemaBuilder) AnyOf(branches ...*SchemaBuilder) *SchemaBuilder {
+	b.schema.AddValidator(rule.InGroups(rule.AnyOf(b.branches(rule.AnyOfName, branches)...), b.groups...))
+	return b
+}
+
+// AllOf adds a validator passing when the rules of all branches pass
+func (b *SchemaBuilder) AllOf(branches ...*SchemaBuilder) *SchemaBuilder {
+	b.schema.AddValidator(rule.InGroups(rule.AllOf(b.branches(rule.AllOfName, branches)...), b.groups...))
+	return b
+}
+
+// Not adds a validator passing when the rules of branch don't all pass
+func (b *SchemaBuilder) Not(branch *SchemaBuilder) *SchemaBuilder {
+	b.schema.AddValidator(rule.InGroups(rule.Not(b.branches(rule.NotName, []*SchemaBuilder{branch})...), b.groups...))
 	return b
 }
 
+// branches returns the rules of each combinator branch as a single validator, collecting
+// their build errors. Missing branches and branches other than Field() builders, whose fields
+// or elements would be lost, are reported as rule.ErrInvalidSyntax
+func (b *SchemaBuilder) branches(name string, branches []*SchemaBuilder) []schema.Validator {
+	if len(branches) == 0 {
+		b.errs = append(b.errs, rule.BuildError{Rule: name, Err: fmt.Errorf("%w: %s needs at least one branch", rule.ErrInvalidSyntax, name)})
+		return nil
+	}
+
+	validators := make([]schema.Validator, 0, len(branches))
+	for _, branch := range branches {
+		if branch == nil {
+			b.errs = append(b.errs, rule.BuildError{Rule: name, Err: fmt.Errorf("%w: %s branch is nil", rule.ErrInvalidSyntax, name)})
+			continue
+		}
+
+		b.errs = append(b.errs, branch.errs...)
+
+		fs, ok := branch.schema.(*schema.FieldSchema)
+		if !ok {
+			b.errs = append(b.errs, rule.BuildError{Rule: name, Err: fmt.Errorf("%w: %s branches must be Field() builders, got %T", rule.ErrInvalidSyntax, name, branch.schema)})
+			continue
+		}
+
+		switch fieldValidators := fs.Validators(); len(fieldValidators) {
+		case 1:
+			validators = append(validators, fieldValidators[0])
+		default:
+			// Without rules the branch is an allOf of nothing, which always passes
+			validators = append(validators, rule.AllOf(fieldValidators...))
+		}
+	}
+
+	return validators
+}
+
 func (b *SchemaBuilder) WithField(name string, fieldSchema schema.Schema) *SchemaBuilder {
 	if os, ok := b.schema.(*schema.ObjectSchema); ok {
 		os.AddField(name, fieldSchema)
@@ -58,6 +163,52 @@ func (b *SchemaBuilder) WithField(name string, fieldSchema schema.Schema) *Schem
 	return b
 }
 
+// When adds a conditional to an object schema, whose branches are set by the following Then and Else
+//
+//	Object().
+//		WithField("country", Field().Required().Build()).
+//		When(schema.Eq("country", "US")).
+//		Then(Object().WithField("zipCode", Field().AddValidator("len", 5).Build()).Build()).
+//		Else(Object().WithField("zipCode", Field().AddValidator("max", 10).Build()).Build())
+func (b *SchemaBuilder) When(condition schema.Condition) *SchemaBuilder {
+	if os, ok := b.schema.(*schema.ObjectSchema); ok {
+		b.conditional = os.When(condition)
+	}
+	return b
+}
+
+// Then sets the schema validated when the condition of the last When matches
+func (b *SchemaBuilder) Then(thenSchema schema.Schema) *SchemaBuilder {
+	if b.conditional != nil {
+		b.conditional.Then(thenSchema)
+	}
+	return b
+}
+
+// Else sets the schema validated when the condition of the last When doesn't match
+func (b *SchemaBuilder) Else(elseSchema schema.Schema) *SchemaBuilder {
+	if b.conditional != nil {
+		b.conditional.Else(elseSchema)
+	}
+	return b
+}
+
+// Variant sets the schema of a union for a discriminator value
+func (b *SchemaBuilder) Variant(key string, variantSchema schema.Schema) *SchemaBuilder {
+	if us, ok := b.schema.(*schema.UnionSchema); ok {
+		us.AddVariant(key, variantSchema)
+	}
+	return b
+}
+
+// Fallback sets the schema of a union for discriminator values without a variant
+func (b *SchemaBuilder) Fallback(fallbackSchema schema.Schema) *SchemaBuilder {
+	if us, ok := b.schema.(*schema.UnionSchema); ok {
+		us.SetFallback(fallbackSchema)
+	}
+	return b
+}
+
 // FieldName sets the mapping for an object field
 func (b *SchemaBuilder) FieldName(name string, fieldName string) *SchemaBuilder {
 	if os, ok := b.schema.(*schema.ObjectSchema); ok {
@@ -67,6 +218,22 @@ func (b *SchemaBuilder) FieldName(name string, fieldName string) *SchemaBuilder
 }
 
 // Build returns the built schema
+// It panics if any validator failed to build, see BuildE
 func (b *SchemaBuilder) Build() schema.Schema {
-	return b.schema
+	s, err := b.BuildE()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return s
+}
+
+// BuildE returns the built schema, or a rule.BuildErrors listing every
+// validator that failed to build
+func (b *SchemaBuilder) BuildE() (schema.Schema, error) {
+	if len(b.errs) > 0 {
+		return nil, b.errs
+	}
+
+	return b.schema, nil
 }
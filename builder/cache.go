@@ -0,0 +1,55 @@
+package builder
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/weilence/schema-validator/schema"
+)
+
+// SchemaCache is a concurrent-safe cache of compiled struct schemas keyed by reflect.Type
+// Each ParseConfig owns its own cache, so entries are effectively keyed by type and config
+type SchemaCache struct {
+	mu      sync.RWMutex
+	schemas map[reflect.Type]*schema.ObjectSchema
+}
+
+// NewSchemaCache creates an empty schema cache
+func NewSchemaCache() *SchemaCache {
+	return &SchemaCache{
+		schemas: make(map[reflect.Type]*schema.ObjectSchema),
+	}
+}
+
+// Get returns the cached schema of a struct type
+func (c *SchemaCache) Get(rt reflect.Type) (*schema.ObjectSchema, bool) {
+	if rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	s, ok := c.schemas[rt]
+	return s, ok
+}
+
+// Len returns the number of cached schemas
+func (c *SchemaCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.schemas)
+}
+
+// store adds fully resolved schemas to the cache, keeping entries stored concurrently by another parse
+func (c *SchemaCache) store(schemas map[reflect.Type]*schema.ObjectSchema) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for rt, s := range schemas {
+		if _, ok := c.schemas[rt]; !ok {
+			c.schemas[rt] = s
+		}
+	}
+}
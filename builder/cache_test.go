@@ -0,0 +1,78 @@
+package builder
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cachedAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type cachedUser struct {
+	Home cachedAddress `json:"home"`
+	Work cachedAddress `json:"work"`
+}
+
+type cachedCompany struct {
+	Office cachedAddress `json:"office"`
+}
+
+func TestSchemaCache_SharesNestedTypes(t *testing.T) {
+	cfg := NewParseConfig()
+
+	user, err := ParseWithConfig(reflect.TypeFor[cachedUser](), cfg)
+	assert.NoError(t, err)
+	company, err := ParseWithConfig(reflect.TypeFor[*cachedCompany](), cfg)
+	assert.NoError(t, err)
+
+	assert.Same(t, user.Field("home"), user.Field("work"))
+	assert.Same(t, user.Field("home"), company.Field("office"))
+	assert.Equal(t, 3, cfg.Cache().Len())
+
+	again, err := ParseWithConfig(reflect.TypeFor[cachedUser](), cfg)
+	assert.NoError(t, err)
+	assert.Same(t, user, again)
+
+	cached, ok := cfg.Cache().Get(reflect.TypeFor[*cachedAddress]())
+	assert.True(t, ok)
+	assert.Same(t, user.Field("home"), cached)
+
+	// A different config never shares compiled schemas
+	other, err := ParseWithConfig(reflect.TypeFor[cachedUser](), NewParseConfig())
+	assert.NoError(t, err)
+	assert.NotSame(t, user, other)
+}
+
+func TestSchemaCache_Concurrent(t *testing.T) {
+	cfg := NewParseConfig()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := ParseWithConfig(reflect.TypeFor[cachedUser](), cfg)
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := ParseWithConfig(reflect.TypeFor[cachedCompany](), cfg)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 3, cfg.Cache().Len())
+}
+
+func TestParseWithConfig_NonStruct(t *testing.T) {
+	_, err := ParseWithConfig(reflect.TypeFor[map[string]int](), NewParseConfig())
+	assert.Error(t, err)
+
+	_, err = ParseWithConfig(nil, NewParseConfig())
+	assert.Error(t, err)
+}
@@ -0,0 +1,445 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/weilence/schema-validator/rule"
+	"github.com/weilence/schema-validator/schema"
+)
+
+// ErrInvalidFile is returned for schema files that don't follow the file format
+var ErrInvalidFile = errors.New("invalid schema file")
+
+// FileError is a problem at a position in a schema file
+type FileError struct {
+	File   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e FileError) Unwrap() error {
+	return e.Err
+}
+
+// Error implements the error interface
+func (e FileError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", e.File, e.Err)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %v", e.File, e.Line, e.Column, e.Err)
+}
+
+// FileErrors holds every problem found while loading schema files
+type FileErrors []FileError
+
+func (e FileErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+func (e FileErrors) Error() string {
+	return errors.Join(e.Unwrap()...).Error()
+}
+
+// LoadSchema compiles the schema file at name in fsys, a YAML or JSON document such as
+//
+//	include: [common.yaml]
+//	definitions:
+//	  address:
+//	    fields:
+//	      city: required|max=50
+//	schema:
+//	  fields:
+//	    name: required|min=2
+//	    tags:
+//	      rules: max=5
+//	      items: min=3
+//	    labels:
+//	      keys: alpha
+//	      values: max=10
+//	    home:
+//	      $ref: address
+//	      rules: required
+//	    work:
+//	      $ref: common.yaml#address
+//
+// A node is either a rule string in tag syntax, a list of rule strings, or a mapping with
+// rules and one of fields (object), items (array), keys and values (map) or $ref. The
+// optional type key names the kind explicitly as field, object, array or map.
+//
+// A $ref names a definition of the same file, of an included file, or of another file
+// as path#name, where paths are relative to the referring file. Definitions are shared
+// and may be recursive; rules next to a $ref apply to that use only.
+// Rules are compiled with the registry and tag parser of the options, and every problem
+// is reported together as FileErrors with the file and line it was found at
+func LoadSchema(fsys fs.FS, name string, opts ...ParseOption) (schema.Schema, error) {
+	l := &fileLoader{
+		fsys:  fsys,
+		cfg:   NewParseConfig(opts...),
+		files: make(map[string]*schemaFile),
+	}
+
+	root := l.load(path.Clean(name), nil, "")
+	if root == nil {
+		return nil, l.errs
+	}
+
+	var s schema.Schema
+	if root.schema == nil {
+		l.errorf(root.path, nil, "%w: no schema key", ErrInvalidFile)
+	} else {
+		s = l.compile(root, root.schema)
+	}
+
+	// Definitions are compiled even when unused so that every file is checked;
+	// refs to other files load more files while this runs
+	for i := 0; i < len(l.order); i++ {
+		f := l.order[i]
+		for _, def := range f.defOrder {
+			f.compiled[def] = l.compile(f, f.defs[def])
+		}
+	}
+
+	for _, ref := range l.refs {
+		if target := l.lookup(ref.file, ref.name, map[*schemaFile]bool{}); target != nil {
+			ref.schema.Resolve(target)
+		} else {
+			l.errorf(ref.from.path, ref.node, "%w: undefined definition %q", ErrInvalidFile, ref.name)
+		}
+	}
+
+	for _, ref := range l.refs {
+		if ref.schema.Cycles() {
+			l.errorf(ref.from.path, ref.node, "%w: circular $ref %q", ErrInvalidFile, ref.name)
+		}
+	}
+
+	if len(l.errs) > 0 {
+		return nil, l.errs
+	}
+
+	return s, nil
+}
+
+// schemaFile is a parsed schema file
+type schemaFile struct {
+	path     string
+	includes []*schemaFile
+	schema   *yaml.Node
+
+	defs     map[string]*yaml.Node
+	defOrder []string
+	compiled map[string]schema.Schema
+}
+
+type fileRef struct {
+	schema *schema.RefSchema
+	from   *schemaFile // file holding the $ref
+	node   *yaml.Node
+	file   *schemaFile // file holding the definition
+	name   string
+}
+
+// fileLoader holds the state of a single LoadSchema call
+type fileLoader struct {
+	fsys  fs.FS
+	cfg   *ParseConfig
+	files map[string]*schemaFile
+	order []*schemaFile
+	refs  []fileRef
+	errs  FileErrors
+}
+
+func (l *fileLoader) errorf(file string, node *yaml.Node, format string, args ...any) {
+	err := FileError{File: file, Err: fmt.Errorf(format, args...)}
+	if node != nil {
+		err.Line, err.Column = node.Line, node.Column
+	}
+
+	l.errs = append(l.errs, err)
+}
+
+// load parses the file at name, reporting read errors at node of the file from
+func (l *fileLoader) load(name string, node *yaml.Node, from string) *schemaFile {
+	if f, ok := l.files[name]; ok {
+		return f
+	}
+
+	content, err := fs.ReadFile(l.fsys, name)
+	if err != nil {
+		if from == "" {
+			l.errs = append(l.errs, FileError{File: name, Err: err})
+		} else {
+			l.errorf(from, node, "%w", err)
+		}
+		return nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		l.errs = append(l.errs, FileError{File: name, Err: err})
+		return nil
+	}
+
+	f := &schemaFile{
+		path:     name,
+		defs:     make(map[string]*yaml.Node),
+		compiled: make(map[string]schema.Schema),
+	}
+	// Registered before includes are loaded, so include cycles end here
+	l.files[name] = f
+	l.order = append(l.order, f)
+
+	if len(doc.Content) == 0 {
+		return f
+	}
+
+	top := doc.Content[0]
+	if top.Kind != yaml.MappingNode {
+		l.errorf(name, top, "%w: expected a mapping with schema, definitions or include", ErrInvalidFile)
+		return f
+	}
+
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		key, value := top.Content[i], top.Content[i+1]
+		switch key.Value {
+		case "schema":
+			f.schema = value
+		case "definitions":
+			if value.Kind != yaml.MappingNode {
+				l.errorf(name, value, "%w: definitions must be a mapping", ErrInvalidFile)
+				continue
+			}
+
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				defName := value.Content[j].Value
+				if _, ok := f.defs[defName]; ok {
+					l.errorf(name, value.Content[j], "%w: duplicate definition %q", ErrInvalidFile, defName)
+					continue
+				}
+
+				f.defs[defName] = value.Content[j+1]
+				f.defOrder = append(f.defOrder, defName)
+			}
+		case "include":
+			includes := []*yaml.Node{value}
+			if value.Kind == yaml.SequenceNode {
+				includes = value.Content
+			}
+
+			for _, include := range includes {
+				if include.Kind != yaml.ScalarNode {
+					l.errorf(name, include, "%w: include must be a path or a list of paths", ErrInvalidFile)
+					continue
+				}
+
+				if included := l.load(relativePath(name, include.Value), include, name); included != nil {
+					f.includes = append(f.includes, included)
+				}
+			}
+		default:
+			l.errorf(name, key, "%w: unknown key %q", ErrInvalidFile, key.Value)
+		}
+	}
+
+	return f
+}
+
+// lookup finds a compiled definition in f, then in the files it includes
+func (l *fileLoader) lookup(f *schemaFile, name string, visited map[*schemaFile]bool) schema.Schema {
+	if f == nil || visited[f] {
+		return nil
+	}
+	visited[f] = true
+
+	if s, ok := f.compiled[name]; ok {
+		return s
+	}
+
+	for _, include := range f.includes {
+		if s := l.lookup(include, name, visited); s != nil {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// compile compiles a schema node of f
+func (l *fileLoader) compile(f *schemaFile, node *yaml.Node) schema.Schema {
+	switch node.Kind {
+	case yaml.ScalarNode, yaml.SequenceNode:
+		return l.withRules(f, schema.NewField(), node)
+	case yaml.MappingNode:
+	default:
+		l.errorf(f.path, node, "%w: expected rules or a mapping", ErrInvalidFile)
+		return schema.NewField()
+	}
+
+	keys := make(map[string]*yaml.Node)
+	var rules *yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "rules":
+			rules = value
+		case "type", "fields", "items", "keys", "values", "$ref":
+			keys[key.Value] = value
+		default:
+			l.errorf(f.path, key, "%w: unknown key %q", ErrInvalidFile, key.Value)
+		}
+	}
+
+	kind := ""
+	for _, k := range []struct{ key, kind string }{
+		{"$ref", "ref"},
+		{"fields", "object"},
+		{"items", "array"},
+		{"keys", "map"},
+		{"values", "map"},
+	} {
+		value, ok := keys[k.key]
+		if !ok {
+			continue
+		}
+
+		if kind != "" && kind != k.kind {
+			l.errorf(f.path, value, "%w: %s can't be used in %s schemas", ErrInvalidFile, k.key, kind)
+			continue
+		}
+		kind = k.kind
+	}
+
+	if t, ok := keys["type"]; ok {
+		switch {
+		case t.Value != "field" && t.Value != "object" && t.Value != "array" && t.Value != "map":
+			l.errorf(f.path, t, "%w: unknown type %q", ErrInvalidFile, t.Value)
+		case kind != "" && kind != t.Value:
+			l.errorf(f.path, t, "%w: type %s doesn't match the schema keys", ErrInvalidFile, t.Value)
+		default:
+			kind = t.Value
+		}
+	}
+
+	var s schema.Schema
+	switch kind {
+	case "ref":
+		s = l.ref(f, keys["$ref"])
+	case "object":
+		obj := schema.NewObject()
+		if fields, ok := keys["fields"]; ok {
+			if fields.Kind != yaml.MappingNode {
+				l.errorf(f.path, fields, "%w: fields must be a mapping", ErrInvalidFile)
+			}
+
+			for i := 0; fields.Kind == yaml.MappingNode && i+1 < len(fields.Content); i += 2 {
+				name := fields.Content[i]
+				if obj.Field(name.Value) != nil {
+					l.errorf(f.path, name, "%w: duplicate field %q", ErrInvalidFile, name.Value)
+					continue
+				}
+
+				obj.AddField(name.Value, l.compile(f, fields.Content[i+1]))
+			}
+		}
+		s = obj
+	case "array":
+		var element schema.Schema = schema.NewField()
+		if items, ok := keys["items"]; ok {
+			element = l.compile(f, items)
+		}
+		s = schema.NewArray(element)
+	case "map":
+		var key, value schema.Schema
+		if n, ok := keys["keys"]; ok {
+			key = l.compile(f, n)
+		}
+		if n, ok := keys["values"]; ok {
+			value = l.compile(f, n)
+		}
+		s = schema.NewMap(key, value)
+	default:
+		s = schema.NewField()
+	}
+
+	if rules == nil {
+		return s
+	}
+
+	return l.withRules(f, s, rules)
+}
+
+// ref returns an unresolved reference for a $ref value, resolved once all files are compiled
+func (l *fileLoader) ref(f *schemaFile, node *yaml.Node) schema.Schema {
+	ref := schema.NewRef()
+	if node.Kind != yaml.ScalarNode || node.Value == "" {
+		l.errorf(f.path, node, "%w: $ref must be a definition name", ErrInvalidFile)
+		return ref.Resolve(schema.NewField())
+	}
+
+	target, name := f, node.Value
+	if file, def, ok := strings.Cut(node.Value, "#"); ok {
+		name = def
+		if file != "" {
+			target = l.load(relativePath(f.path, file), node, f.path)
+		}
+	}
+
+	if target == nil {
+		return ref.Resolve(schema.NewField())
+	}
+
+	l.refs = append(l.refs, fileRef{schema: ref, from: f, node: node, file: target, name: name})
+	return ref
+}
+
+// withRules adds the validators of a rule string or list of rule strings to s
+func (l *fileLoader) withRules(f *schemaFile, s schema.Schema, node *yaml.Node) schema.Schema {
+	entries := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		entries = node.Content
+	}
+
+	for _, entry := range entries {
+		if entry.Kind != yaml.ScalarNode {
+			l.errorf(f.path, entry, "%w: rules must be a string or a list of strings", ErrInvalidFile)
+			continue
+		}
+
+		if entry.Tag == "!!null" {
+			continue
+		}
+
+		validators, err := NewValidators(l.cfg.ParseTag(entry.Value), l.cfg)
+		var buildErrs rule.BuildErrors
+		if errors.As(err, &buildErrs) {
+			for _, buildErr := range buildErrs {
+				l.errorf(f.path, entry, "%w", buildErr)
+			}
+			continue
+		}
+
+		for _, v := range validators {
+			s.AddValidator(v)
+		}
+	}
+
+	return s
+}
+
+// relativePath resolves name relative to the directory of the file from
+func relativePath(from, name string) string {
+	return path.Join(path.Dir(from), name)
+}
@@ -0,0 +1,219 @@
+package builder
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weilence/schema-validator/rule"
+	"github.com/weilence/schema-validator/schema"
+)
+
+var schemaFiles = fstest.MapFS{
+	"schemas/user.yaml": {Data: []byte(`
+include: common/address.yaml
+definitions:
+  user:
+    fields:
+      name: required|min=2
+      email:
+        - required
+        - email
+      tags:
+        rules: omitempty|max=2
+        items: min=3
+      labels:
+        keys: alpha
+        values: max=5
+        rules: omitempty
+      home:
+        $ref: address
+        rules: required
+      work:
+        $ref: common/contact.json#contact
+      friends:
+        items:
+          $ref: user
+schema:
+  $ref: user
+`)},
+	"schemas/common/address.yaml": {Data: []byte(`
+definitions:
+  address:
+    type: object
+    fields:
+      city: required|max=10
+`)},
+	"schemas/common/contact.json": {Data: []byte(`{
+  "definitions": {
+    "contact": {"fields": {"phone": "required|e164"}}
+  }
+}`)},
+}
+
+func TestLoadSchema(t *testing.T) {
+	s, err := LoadSchema(schemaFiles, "schemas/user.yaml")
+	assert.NoError(t, err)
+
+	valid := map[string]any{
+		"name":   "ann",
+		"email":  "ann@example.com",
+		"tags":   []any{"abc"},
+		"labels": map[string]any{"env": "prod"},
+		"home":   map[string]any{"city": "Paris"},
+		"work":   map[string]any{"phone": "+33123456"},
+		"friends": []any{map[string]any{
+			"name":  "bob",
+			"email": "bob@example.com",
+			"home":  map[string]any{"city": "Rome"},
+			"work":  map[string]any{"phone": "+39123456"},
+		}},
+	}
+	assert.Empty(t, validate(t, s, valid))
+
+	invalid := map[string]any{
+		"name":   "a",
+		"email":  "nope",
+		"tags":   []any{"ab", "abc", "abcd"},
+		"labels": map[string]any{"env1": "production"},
+		"work":   map[string]any{"phone": "123"},
+		"friends": []any{map[string]any{
+			"name":  "bob",
+			"email": "bob@example.com",
+			"home":  map[string]any{},
+			"work":  map[string]any{"phone": "+39123456"},
+		}},
+	}
+
+	var got []string
+	for _, e := range validate(t, s, invalid) {
+		got = append(got, e.Path+": "+e.Code)
+	}
+	assert.Equal(t, []string{
+		"name: min",
+		"email: email",
+		"tags: max",
+		"tags[0]: min",
+		"labels[env1].$key: alpha",
+		"labels[env1]: max",
+		"home: required",
+		"work.phone: e164",
+		"friends[0].home.city: required",
+	}, got)
+}
+
+func TestLoadSchema_Errors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.yaml": {Data: []byte(`include: [missing.yaml]
+schema:
+  fields:
+    name: required|nosuchrule
+    age:
+      - min=1
+      - between=1,2
+    home:
+      $ref: nowhere
+    misc:
+      fields: {}
+      items: required
+      colour: red
+`)},
+	}
+
+	_, err := LoadSchema(fsys, "main.yaml")
+
+	var errs FileErrors
+	assert.True(t, errors.As(err, &errs))
+
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{
+		`main.yaml:1:11: open missing.yaml: file does not exist`,
+		`main.yaml:4:11: rule "nosuchrule": validator not found in registry`,
+		`main.yaml:7:9: rule "between": validator not found in registry`,
+		`main.yaml:13:7: invalid schema file: unknown key "colour"`,
+		`main.yaml:12:14: invalid schema file: items can't be used in object schemas`,
+		`main.yaml:9:13: invalid schema file: undefined definition "nowhere"`,
+	}, got)
+	assert.ErrorIs(t, err, rule.ErrNotFound)
+}
+
+func TestLoadSchema_CircularRef(t *testing.T) {
+	fsys := fstest.MapFS{
+		"mutual.yaml": {Data: []byte(`definitions:
+  a:
+    $ref: b
+  b:
+    $ref: a
+schema:
+  $ref: a
+`)},
+		"self.yaml": {Data: []byte(`definitions:
+  a:
+    $ref: a
+schema:
+  fields:
+    name: required
+`)},
+	}
+
+	_, err := LoadSchema(fsys, "mutual.yaml")
+	assert.EqualError(t, err, `mutual.yaml:7:9: invalid schema file: circular $ref "a"
+mutual.yaml:3:11: invalid schema file: circular $ref "b"
+mutual.yaml:5:11: invalid schema file: circular $ref "a"`)
+	assert.ErrorIs(t, err, ErrInvalidFile)
+
+	_, err = LoadSchema(fsys, "self.yaml")
+	assert.EqualError(t, err, `self.yaml:3:11: invalid schema file: circular $ref "a"`)
+}
+
+func TestLoadSchema_RecursiveCollections(t *testing.T) {
+	fsys := fstest.MapFS{
+		"tree.yaml": {Data: []byte(`definitions:
+  tree:
+    items:
+      $ref: tree
+  dict:
+    values:
+      $ref: dict
+schema:
+  fields:
+    tree:
+      $ref: tree
+    dict:
+      $ref: dict
+`)},
+	}
+
+	s, err := LoadSchema(fsys, "tree.yaml")
+	assert.NoError(t, err)
+
+	var paths []string
+	err = schema.Walk(s, func(path string, _ schema.Schema) error {
+		paths = append(paths, path)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"", "tree", "tree", "tree[*]", "dict", "dict", "dict[*]"}, paths)
+}
+
+func TestLoadSchema_Registry(t *testing.T) {
+	r := rule.NewRegistry()
+	rule.RegisterDefault(r)
+	r.Register("slug", func(ctx *schema.Context) error {
+		if ctx.Value().String() == "Not a slug" {
+			return schema.ErrCheckFailed
+		}
+		return nil
+	})
+
+	fsys := fstest.MapFS{"page.yaml": {Data: []byte("schema:\n  fields:\n    slug: slug\n")}}
+	s, err := LoadSchema(fsys, "page.yaml", WithRegistry(r))
+	assert.NoError(t, err)
+
+	errs := validate(t, s, map[string]any{"slug": "Not a slug"})
+	assert.True(t, errs.HasErrorCode("slug"))
+}
@@ -1,31 +1,142 @@
 package builder
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"slices"
 	"strings"
 	"time"
 
+	"github.com/weilence/schema-validator/data"
 	"github.com/weilence/schema-validator/rule"
 	"github.com/weilence/schema-validator/schema"
 	"github.com/weilence/schema-validator/tag"
 )
 
+// ErrInvalidOption is returned by Parse for parse options given invalid arguments
+var ErrInvalidOption = errors.New("invalid parse option")
+
+// TypeHandler builds the schema for fields of a specific Go type
+// It may call ParseField to build schemas for nested types with the same config
+type TypeHandler func(fieldType reflect.Type, rules []tag.Rule, cfg *ParseConfig) (schema.Schema, error)
+
+// NameFunc resolves the schema field name of a struct field
+// An empty name skips the field
+type NameFunc func(field reflect.StructField) string
+
+// ParseConfig configures how struct tags are compiled into schemas
 type ParseConfig struct {
 	Registry   *rule.Registry
 	TagParser  *tag.Parser
 	DiveTag    string
 	ValueTypes []reflect.Type
+
+	// KeysTag and EndKeysTag enclose the rules for map keys after DiveTag
+	KeysTag    string
+	EndKeysTag string
+
+	// TypeHandlers builds schemas for specific field types, bypassing the default handling
+	TypeHandlers map[reflect.Type]TypeHandler
+
+	// NameFunc resolves field names, defaults to json, param and query tags then the Go name
+	NameFunc NameFunc
+
+	// DiveTags overrides DiveTag for specific slice or array types
+	DiveTags map[reflect.Type]string
+
+	// Implementations lists the concrete types of interface field types, whose fields
+	// validate according to their dynamic type through a type union
+	Implementations map[reflect.Type][]reflect.Type
+
+	// GroupParents lists the groups inherited by each validation group, see ObjectSchema.InheritGroup
+	GroupParents map[string][]string
+
+	cache *SchemaCache
+	state *parseState
+
+	// errs holds the problems with the options, reported by every Parse call
+	errs rule.BuildErrors
+}
+
+// parseState tracks the struct types being parsed in a single Parse call
+type parseState struct {
+	// pending holds the types being parsed, with the reference schemas that refer back to them
+	pending map[reflect.Type][]*schema.RefSchema
+
+	// embedding holds the struct types being flattened into the current object
+	embedding map[reflect.Type]bool
+
+	// compiled holds the struct types completed by this call, committed to the cache on success
+	compiled map[reflect.Type]*schema.ObjectSchema
+}
+
+func newParseState() *parseState {
+	return &parseState{
+		pending:   make(map[reflect.Type][]*schema.RefSchema),
+		embedding: make(map[reflect.Type]bool),
+		compiled:  make(map[reflect.Type]*schema.ObjectSchema),
+	}
+}
+
+// withState returns a copy of cfg carrying its own parse state
+func (cfg *ParseConfig) withState() *ParseConfig {
+	if cfg.state != nil {
+		return cfg
+	}
+
+	c := *cfg
+	c.state = newParseState()
+	return &c
+}
+
+// ParseTag parses a rule tag with the TagParser, keeping whole the params of rules taking a single
+// text param such as expr, see rule.Registry.TakesTextParam
+func (cfg *ParseConfig) ParseTag(rules string) []tag.Rule {
+	return cfg.TagParser.ParseFunc(rules, cfg.Registry.TakesTextParam)
 }
 
 func defaultParseConfig() *ParseConfig {
 	return &ParseConfig{
-		Registry:   rule.DefaultRegistry(),
-		TagParser:  tag.NewParser(tag.DefaultConfig()),
-		DiveTag:    "dive",
-		ValueTypes: []reflect.Type{reflect.TypeFor[time.Time]()},
+		Registry:     rule.DefaultRegistry(),
+		TagParser:    tag.NewParser(tag.DefaultConfig()),
+		DiveTag:      "dive",
+		KeysTag:      "keys",
+		EndKeysTag:   "endkeys",
+		ValueTypes:   []reflect.Type{reflect.TypeFor[time.Time]()},
+		TypeHandlers: make(map[reflect.Type]TypeHandler),
+		NameFunc:     getFieldName,
+		DiveTags:     make(map[reflect.Type]string),
+		cache:        NewSchemaCache(),
+
+		Implementations: make(map[reflect.Type][]reflect.Type),
+		GroupParents:    make(map[string][]string),
+	}
+}
+
+// Cache returns the schema cache of the config, nil if caching is disabled
+// Configs built with NewParseConfig always have a cache
+func (cfg *ParseConfig) Cache() *SchemaCache {
+	return cfg.cache
+}
+
+// NewParseConfig returns the default config with opts applied
+// Options must not be changed after the config is first used, as compiled schemas are cached
+func NewParseConfig(opts ...ParseOption) *ParseConfig {
+	cfg := defaultParseConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+func (cfg *ParseConfig) diveTag(rt reflect.Type) string {
+	if diveTag, ok := cfg.DiveTags[rt]; ok {
+		return diveTag
 	}
+
+	return cfg.DiveTag
 }
 
 type ParseOption func(*ParseConfig)
@@ -54,19 +165,122 @@ func WithDiveTag(diveTag string) ParseOption {
 	}
 }
 
+// WithKeysTags sets the keywords enclosing the rules for map keys
+func WithKeysTags(keysTag, endKeysTag string) ParseOption {
+	return func(cfg *ParseConfig) {
+		cfg.KeysTag = keysTag
+		cfg.EndKeysTag = endKeysTag
+	}
+}
+
 func WithValueTypes(types ...reflect.Type) ParseOption {
 	return func(cfg *ParseConfig) {
 		cfg.ValueTypes = append(cfg.ValueTypes, types...)
 	}
 }
 
+// WithTypeHandler registers a handler that builds the schema for fields of type rt
+func WithTypeHandler(rt reflect.Type, handler TypeHandler) ParseOption {
+	return func(cfg *ParseConfig) {
+		cfg.TypeHandlers[rt] = handler
+	}
+}
+
+// WithNameFunc sets how schema field names are resolved from struct fields
+func WithNameFunc(fn NameFunc) ParseOption {
+	return func(cfg *ParseConfig) {
+		cfg.NameFunc = fn
+	}
+}
+
+// WithTypeDiveTag sets the dive keyword for a specific slice or array type
+func WithTypeDiveTag(rt reflect.Type, diveTag string) ParseOption {
+	return func(cfg *ParseConfig) {
+		cfg.DiveTags[rt] = diveTag
+	}
+}
+
+// WithImplementations registers concrete types of the interface type iface, such as any
+// Fields of type iface validate according to the schema of their dynamic type, and values
+// of other types are reported with schema.UnknownDiscriminatorCode
+// Parse returns an ErrInvalidOption error if iface is not an interface or an implementation
+// doesn't implement it
+func WithImplementations(iface reflect.Type, impls ...reflect.Type) ParseOption {
+	return func(cfg *ParseConfig) {
+		if iface.Kind() != reflect.Interface {
+			cfg.errs = append(cfg.errs, rule.BuildError{
+				Type: iface,
+				Err:  fmt.Errorf("%w: WithImplementations: %s is not an interface type", ErrInvalidOption, iface),
+			})
+			return
+		}
+
+		for _, impl := range impls {
+			if !impl.Implements(iface) {
+				cfg.errs = append(cfg.errs, rule.BuildError{
+					Type: impl,
+					Err:  fmt.Errorf("%w: WithImplementations: %s does not implement %s", ErrInvalidOption, impl, iface),
+				})
+				continue
+			}
+
+			cfg.Implementations[iface] = append(cfg.Implementations[iface], impl)
+		}
+	}
+}
+
+// WithGroupInheritance makes the validation group inherit the rules of parents in parsed schemas
+func WithGroupInheritance(group string, parents ...string) ParseOption {
+	return func(cfg *ParseConfig) {
+		cfg.GroupParents[group] = append(cfg.GroupParents[group], parents...)
+	}
+}
+
+// Parse parses a struct type into an ObjectSchema using struct tags
 func Parse(rt reflect.Type, opts ...ParseOption) (*schema.ObjectSchema, error) {
-	cfg := defaultParseConfig()
-	for _, opt := range opts {
-		opt(cfg)
+	return ParseWithConfig(rt, NewParseConfig(opts...))
+}
+
+// ParseWithConfig parses a struct type into an ObjectSchema using an existing config
+// Compiled schemas, including nested struct types, are cached in the config and
+// shared between calls, so the returned schema must not be modified
+func ParseWithConfig(rt reflect.Type, cfg *ParseConfig) (*schema.ObjectSchema, error) {
+	if rt == nil {
+		return nil, fmt.Errorf("cannot parse schema of nil type")
 	}
 
-	return parse(rt, cfg)
+	if rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	if rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot parse schema of non-struct type %s", rt)
+	}
+
+	if len(cfg.errs) > 0 {
+		return nil, cfg.errs
+	}
+
+	if cfg.cache != nil {
+		if s, ok := cfg.cache.Get(rt); ok {
+			return s, nil
+		}
+	}
+
+	cfg = cfg.withState()
+	objSchema, err := parse(rt, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.cache != nil {
+		cfg.cache.store(cfg.state.compiled)
+		if s, ok := cfg.cache.Get(rt); ok {
+			return s, nil
+		}
+	}
+
+	return objSchema, nil
 }
 
 func parse(rt reflect.Type, cfg *ParseConfig) (*schema.ObjectSchema, error) {
@@ -74,18 +288,64 @@ func parse(rt reflect.Type, cfg *ParseConfig) (*schema.ObjectSchema, error) {
 		rt = rt.Elem()
 	}
 
+	if cfg.cache != nil {
+		if s, ok := cfg.cache.Get(rt); ok {
+			return s, nil
+		}
+	}
+
+	state := cfg.state
+	if s, ok := state.compiled[rt]; ok {
+		return s, nil
+	}
+
+	state.pending[rt] = nil
+	defer delete(state.pending, rt)
+
+	embedding := state.embedding
+	state.embedding = map[reflect.Type]bool{rt: true}
+	defer func() { state.embedding = embedding }()
+
+	var errs rule.BuildErrors
 	objSchema := schema.NewObject()
 	for i := 0; i < rt.NumField(); i++ {
 		field := rt.Field(i)
-		if err := parseStructField(objSchema, field, cfg); err != nil {
-			return nil, err
+		if err := parseStructField(objSchema, rt, field, cfg); err != nil {
+			if !appendBuildErrors(&errs, err) {
+				return nil, err
+			}
 		}
 	}
 
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	for group, parents := range cfg.GroupParents {
+		objSchema.InheritGroup(group, parents...)
+	}
+
+	for _, ref := range state.pending[rt] {
+		ref.Resolve(objSchema)
+	}
+
+	state.compiled[rt] = objSchema
 	return objSchema, nil
 }
 
-func parseStructField(s *schema.ObjectSchema, field reflect.StructField, cfg *ParseConfig) error {
+// parseStruct parses a nested struct type, returning a reference schema if
+// the type is already being parsed further up
+func parseStruct(rt reflect.Type, cfg *ParseConfig) (schema.Schema, error) {
+	if refs, ok := cfg.state.pending[rt]; ok {
+		ref := schema.NewRef()
+		cfg.state.pending[rt] = append(refs, ref)
+		return ref, nil
+	}
+
+	return parse(rt, cfg)
+}
+
+func parseStructField(s *schema.ObjectSchema, owner reflect.Type, field reflect.StructField, cfg *ParseConfig) error {
 	fieldType := field.Type
 	if fieldType.Kind() == reflect.Pointer {
 		fieldType = fieldType.Elem()
@@ -96,13 +356,27 @@ func parseStructField(s *schema.ObjectSchema, field reflect.StructField, cfg *Pa
 			return nil
 		}
 
+		// A struct embedding a pointer to itself would be flattened forever
+		if cfg.state.embedding[fieldType] {
+			return nil
+		}
+		cfg.state.embedding[fieldType] = true
+		defer delete(cfg.state.embedding, fieldType)
+
+		var errs rule.BuildErrors
 		for i := 0; i < fieldType.NumField(); i++ {
 			embeddedField := fieldType.Field(i)
-			if err := parseStructField(s, embeddedField, cfg); err != nil {
-				return err
+			if err := parseStructField(s, fieldType, embeddedField, cfg); err != nil {
+				if !appendBuildErrors(&errs, err) {
+					return err
+				}
 			}
 		}
 
+		if len(errs) > 0 {
+			return errs
+		}
+
 		return nil
 	}
 
@@ -110,29 +384,83 @@ func parseStructField(s *schema.ObjectSchema, field reflect.StructField, cfg *Pa
 		return nil
 	}
 
-	fieldName := getFieldName(field)
+	fieldName := cfg.NameFunc(field)
+	if fieldName == "" {
+		return nil
+	}
+
 	validateTag := field.Tag.Get("validate")
 	if validateTag == "-" {
 		return nil
 	}
 
-	rules := cfg.TagParser.Parse(validateTag)
-	fieldSchema, err := parseField(field.Type, rules, cfg)
+	rules := cfg.ParseTag(validateTag)
+
+	// The groups tag limits the rules without groups of their own
+	if groupsTag := field.Tag.Get("groups"); groupsTag != "" {
+		groups := strings.Split(groupsTag, ",")
+		for i, group := range groups {
+			groups[i] = strings.TrimSpace(group)
+			if groups[i] == "" {
+				return rule.BuildError{
+					Type:  owner,
+					Field: field.Name,
+					Err:   fmt.Errorf("%w: empty group in groups tag %q", rule.ErrInvalidSyntax, groupsTag),
+				}
+			}
+		}
+
+		for i := range rules {
+			if rules[i].Groups == nil {
+				rules[i].Groups = groups
+			}
+		}
+	}
+
+	fieldSchema, err := ParseField(field.Type, rules, cfg)
 	if err != nil {
-		return err
+		var errs rule.BuildErrors
+		if !appendBuildErrors(&errs, err) {
+			return err
+		}
+
+		// Errors from nested struct types already carry their own location
+		for i := range errs {
+			if errs[i].Type == nil {
+				errs[i].Type = owner
+				errs[i].Field = field.Name
+			}
+		}
+
+		return errs
 	}
 
 	s.AddField(fieldName, fieldSchema).AddFieldName(fieldName, field.Name)
 	return nil
 }
 
-func parseField(fieldType reflect.Type, rules []tag.Rule, cfg *ParseConfig) (schema.Schema, error) {
+// ParseField builds the schema for a value of fieldType with the given rules
+func ParseField(fieldType reflect.Type, rules []tag.Rule, cfg *ParseConfig) (schema.Schema, error) {
+	cfg = cfg.withState()
+
+	if handler, ok := cfg.TypeHandlers[fieldType]; ok {
+		return handler(fieldType, rules, cfg)
+	}
+
 	if fieldType.Kind() == reflect.Pointer {
 		fieldType = fieldType.Elem()
+		if handler, ok := cfg.TypeHandlers[fieldType]; ok {
+			return handler(fieldType, rules, cfg)
+		}
+	}
+
+	if impls, ok := cfg.Implementations[fieldType]; ok {
+		return parseInterface(impls, rules, cfg)
 	}
 
 	if fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array {
-		diveIdx := slices.IndexFunc(rules, func(r tag.Rule) bool { return r.Name == cfg.DiveTag })
+		diveTag := cfg.diveTag(fieldType)
+		diveIdx := slices.IndexFunc(rules, func(r tag.Rule) bool { return r.Name == diveTag })
 		var arrayRules, itemRules []tag.Rule
 		if diveIdx >= 0 {
 			arrayRules = rules[:diveIdx]
@@ -141,49 +469,356 @@ func parseField(fieldType reflect.Type, rules []tag.Rule, cfg *ParseConfig) (sch
 			arrayRules = rules
 		}
 
-		elemSchema, err := parseField(fieldType.Elem(), itemRules, cfg)
-		if err != nil {
+		var errs rule.BuildErrors
+		elemSchema, err := ParseField(fieldType.Elem(), itemRules, cfg)
+		if err != nil && !appendBuildErrors(&errs, err) {
 			return nil, err
 		}
 
+		validators, err := NewValidators(arrayRules, cfg)
+		if err != nil && !appendBuildErrors(&errs, err) {
+			return nil, err
+		}
+
+		if len(errs) > 0 {
+			return nil, errs
+		}
+
 		arraySchema := schema.NewArray(elemSchema)
-		for _, rule := range arrayRules {
-			params := convertValidatorParams(rule.Name, rule.Params, cfg)
-			v := cfg.Registry.NewValidator(rule.Name, params...)
-			if v != nil {
-				arraySchema.AddValidator(v)
-			}
+		for _, v := range validators {
+			arraySchema.AddValidator(v)
 		}
 
 		return arraySchema, nil
 	}
 
 	if fieldType.Kind() == reflect.Struct && !slices.Contains(cfg.ValueTypes, fieldType) {
-		return parse(fieldType, cfg)
+		var errs rule.BuildErrors
+		objSchema, err := parseStruct(fieldType, cfg)
+		if err != nil && !appendBuildErrors(&errs, err) {
+			return nil, err
+		}
+
+		validators, err := NewValidators(rules, cfg)
+		if err != nil && !appendBuildErrors(&errs, err) {
+			return nil, err
+		}
+
+		if len(errs) > 0 {
+			return nil, errs
+		}
+
+		return withObjectValidators(objSchema, validators), nil
 	}
 
 	if fieldType.Kind() == reflect.Map {
-		return schema.NewObject(), nil
+		return parseMap(fieldType, rules, cfg)
+	}
+
+	validators, err := NewValidators(rules, cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	fieldSchema := schema.NewField()
-	for _, rule := range rules {
-		params := convertValidatorParams(rule.Name, rule.Params, cfg)
-		v := cfg.Registry.NewValidator(rule.Name, params...)
-		if v != nil {
-			fieldSchema.AddValidator(v)
-		}
+	for _, v := range validators {
+		fieldSchema.AddValidator(v)
 	}
 
 	return fieldSchema, nil
 }
 
-func convertValidatorParams(name string, paramStrs []string, cfg *ParseConfig) []any {
-	paramTypes := cfg.Registry.GetValidatorParamTypes(name)
+// parseInterface builds a type union with a variant per implementation of an interface field type
+func parseInterface(impls []reflect.Type, rules []tag.Rule, cfg *ParseConfig) (schema.Schema, error) {
+	var errs rule.BuildErrors
+	union := schema.NewTypeUnion()
+	for _, impl := range impls {
+		variant, err := ParseField(impl, nil, cfg)
+		if err != nil && !appendBuildErrors(&errs, err) {
+			return nil, err
+		}
+
+		union.AddVariant(schema.TypeKey(impl), variant)
+	}
+
+	validators, err := NewValidators(rules, cfg)
+	if err != nil && !appendBuildErrors(&errs, err) {
+		return nil, err
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	for _, v := range validators {
+		union.AddValidator(v)
+	}
+
+	return union, nil
+}
+
+// parseMap builds a MapSchema from rules of the form
+// mapRules... dive [keys keyRules... endkeys] valueRules...
+func parseMap(fieldType reflect.Type, rules []tag.Rule, cfg *ParseConfig) (schema.Schema, error) {
+	diveTag := cfg.diveTag(fieldType)
+	diveIdx := slices.IndexFunc(rules, func(r tag.Rule) bool { return r.Name == diveTag })
+	var mapRules, keyRules, valueRules []tag.Rule
+	if diveIdx >= 0 {
+		mapRules = rules[:diveIdx]
+		valueRules = rules[diveIdx+1:]
+	} else {
+		mapRules = rules
+	}
+
+	var errs rule.BuildErrors
+	if len(valueRules) > 0 && valueRules[0].Name == cfg.KeysTag {
+		endIdx := slices.IndexFunc(valueRules, func(r tag.Rule) bool { return r.Name == cfg.EndKeysTag })
+		if endIdx < 0 {
+			errs = append(errs, rule.BuildError{
+				Rule: cfg.KeysTag,
+				Err:  fmt.Errorf("%w: %s without %s", rule.ErrInvalidSyntax, cfg.KeysTag, cfg.EndKeysTag),
+			})
+			valueRules = nil
+		} else {
+			keyRules = valueRules[1:endIdx]
+			valueRules = valueRules[endIdx+1:]
+		}
+	}
+
+	var keySchema schema.Schema
+	if len(keyRules) > 0 {
+		var err error
+		keySchema, err = ParseField(fieldType.Key(), keyRules, cfg)
+		if err != nil && !appendBuildErrors(&errs, err) {
+			return nil, err
+		}
+	}
+
+	valueSchema, err := ParseField(fieldType.Elem(), valueRules, cfg)
+	if err != nil && !appendBuildErrors(&errs, err) {
+		return nil, err
+	}
+
+	validators, err := NewValidators(mapRules, cfg)
+	if err != nil && !appendBuildErrors(&errs, err) {
+		return nil, err
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	mapSchema := schema.NewMap(keySchema, valueSchema)
+	for _, v := range validators {
+		mapSchema.AddValidator(v)
+	}
+
+	return mapSchema, nil
+}
+
+// withObjectValidators attaches field-level rules to a struct schema
+// Struct schemas are shared through the cache, so rules go on a copy
+func withObjectValidators(s schema.Schema, validators []schema.Validator) schema.Schema {
+	if len(validators) == 0 {
+		return s
+	}
+
+	if objSchema, ok := s.(*schema.ObjectSchema); ok {
+		s = objSchema.Clone()
+	}
+
+	for _, v := range validators {
+		s.AddValidator(v)
+	}
+
+	return s
+}
+
+// NewValidators builds a validator for every rule, collecting all failures into rule.BuildErrors
+func NewValidators(rules []tag.Rule, cfg *ParseConfig) ([]schema.Validator, error) {
+	var errs rule.BuildErrors
+	validators := make([]schema.Validator, 0, len(rules))
+	for i, r := range rules {
+		if r.Name == rule.WhenName && r.Children == nil {
+			// The rest of the rules are the branches of the conditional
+			v, err := newWhen(r, rules[i+1:], cfg)
+			if err != nil {
+				if !appendBuildErrors(&errs, err) {
+					errs = append(errs, rule.BuildError{Rule: r.Name, Err: err})
+				}
+				break
+			}
+
+			validators = append(validators, rule.InGroups(v, r.Groups...))
+			break
+		}
+
+		if r.Name == rule.ElseName && r.Children == nil {
+			errs = append(errs, rule.BuildError{Rule: r.Name, Err: fmt.Errorf("%w: %s without %s", rule.ErrInvalidSyntax, rule.ElseName, rule.WhenName)})
+			continue
+		}
+
+		if r.Children != nil {
+			v, err := newCombinator(r, cfg)
+			if err != nil {
+				if !appendBuildErrors(&errs, err) {
+					errs = append(errs, rule.BuildError{Rule: r.Name, Err: err})
+				}
+				continue
+			}
+
+			validators = append(validators, rule.InGroups(v, r.Groups...))
+			continue
+		}
+
+		params, err := convertValidatorParams(r.Name, r.Params, cfg)
+		if err != nil {
+			errs = append(errs, rule.BuildError{Rule: r.Name, Err: err})
+			continue
+		}
+
+		v, err := cfg.Registry.NewValidatorE(r.Name, params...)
+		if err != nil {
+			if !appendBuildErrors(&errs, err) {
+				errs = append(errs, rule.BuildError{Rule: r.Name, Err: err})
+			}
+			continue
+		}
+
+		validators = append(validators, rule.InGroups(v, r.Groups...))
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return validators, nil
+}
+
+// newWhen builds the validator of a conditional such as when=country,US,CA|len=5|else|max=10
+// whose branches are the rules following it, split at its else; an else belongs to the closest when
+func newWhen(r tag.Rule, rest []tag.Rule, cfg *ParseConfig) (schema.Validator, error) {
+	if len(r.Params) < 2 {
+		return nil, rule.BuildError{Rule: r.Name, Err: fmt.Errorf("%w: %s needs a field and at least one value", rule.ErrInvalidSyntax, r.Name)}
+	}
+
+	// Paths are only looked up when validating, so their syntax is checked here
+	path := r.Params[0]
+	if rest, ok := strings.CutPrefix(path, schema.RootPathPrefix); ok {
+		path = rest
+	} else {
+		for strings.HasPrefix(path, schema.ParentPathPrefix) {
+			path = path[len(schema.ParentPathPrefix):]
+		}
+	}
+	if segments, err := data.ParsePath(path); err != nil || len(segments) == 0 {
+		if err == nil {
+			err = fmt.Errorf("%w %q: empty path", data.ErrInvalidPath, r.Params[0])
+		}
+		return nil, rule.BuildError{Rule: r.Name, Err: fmt.Errorf("%w: %w", rule.ErrInvalidParams, err)}
+	}
+
+	then, els := rest, []tag.Rule(nil)
+	open := 0
+	for i, next := range rest {
+		if next.Children != nil {
+			continue
+		}
+
+		if next.Name == rule.WhenName {
+			open++
+		} else if next.Name == rule.ElseName {
+			if open == 0 {
+				then, els = rest[:i], rest[i+1:]
+				break
+			}
+			open--
+		}
+	}
+
+	var condition schema.Condition
+	if len(r.Params) == 2 {
+		condition = schema.Eq(r.Params[0], r.Params[1])
+	} else {
+		values := make([]any, len(r.Params)-1)
+		for i, p := range r.Params[1:] {
+			values[i] = p
+		}
+		condition = schema.In(r.Params[0], values...)
+	}
+
+	var errs rule.BuildErrors
+	thenValidators, err := NewValidators(then, cfg)
+	if err != nil && !appendBuildErrors(&errs, err) {
+		return nil, err
+	}
+
+	elseValidators, err := NewValidators(els, cfg)
+	if err != nil && !appendBuildErrors(&errs, err) {
+		return nil, err
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return rule.When(condition, thenValidators, elseValidators), nil
+}
+
+// newCombinator builds the validator of a group rule such as anyOf(hexcolor|rgb)
+func newCombinator(r tag.Rule, cfg *ParseConfig) (schema.Validator, error) {
+	if len(r.Children) == 0 {
+		return nil, rule.BuildError{Rule: r.Name, Err: fmt.Errorf("%w: %s() needs at least one rule", rule.ErrInvalidSyntax, r.Name)}
+	}
+
+	children, err := NewValidators(r.Children, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch r.Name {
+	case rule.AnyOfName:
+		return rule.AnyOf(children...), nil
+	case rule.AllOfName:
+		return rule.AllOf(children...), nil
+	case rule.NotName:
+		return rule.Not(children...), nil
+	default:
+		return nil, rule.BuildError{Rule: r.Name, Err: fmt.Errorf("%w: unknown group %s(), expected anyOf, allOf or not", rule.ErrInvalidSyntax, r.Name)}
+	}
+}
+
+// appendBuildErrors appends err to errs if it is a rule.BuildErrors or rule.BuildError
+func appendBuildErrors(errs *rule.BuildErrors, err error) bool {
+	switch e := err.(type) {
+	case rule.BuildErrors:
+		*errs = append(*errs, e...)
+	case rule.BuildError:
+		*errs = append(*errs, e)
+	default:
+		return false
+	}
+
+	return true
+}
+
+func convertValidatorParams(name string, paramStrs []string, cfg *ParseConfig) ([]any, error) {
+	paramTypes, err := cfg.Registry.GetValidatorParamTypesE(name)
+	if err != nil {
+		return nil, errors.Unwrap(err)
+	}
+
+	if cfg.Registry.TakesRawParams(name) {
+		params := make([]any, len(paramStrs))
+		for i, paramStr := range paramStrs {
+			params[i] = paramStr
+		}
+		return params, nil
+	}
 
 	paramTypesLen := len(paramTypes)
 	if paramTypesLen == 0 && len(paramStrs) != 0 {
-		panic(fmt.Sprintf("%s does not take any parameters", name))
+		return nil, fmt.Errorf("%w: %s does not take any parameters", rule.ErrInvalidParams, name)
 	}
 
 	if paramTypesLen == 1 {
@@ -191,124 +826,148 @@ func convertValidatorParams(name string, paramStrs []string, cfg *ParseConfig) [
 		kind := paramType.Kind()
 		switch kind {
 		case reflect.Array:
+			if len(paramStrs) > paramType.Len() {
+				return nil, fmt.Errorf("%w: %s expected at most %d parameters, got %d", rule.ErrInvalidParams, name, paramType.Len(), len(paramStrs))
+			}
 			res := reflect.ArrayOf(paramType.Len(), paramType.Elem())
 			rv := reflect.New(res).Elem()
 			for i, paramStr := range paramStrs {
-				elem := parseValidatorParam(paramType.Elem(), paramStr)
+				elem, err := parseValidatorParam(paramType.Elem(), paramStr)
+				if err != nil {
+					return nil, err
+				}
 				rv.Index(i).Set(reflect.ValueOf(elem))
 			}
-			return []any{rv.Interface()}
+			return []any{rv.Interface()}, nil
 		case reflect.Slice:
 			res := reflect.MakeSlice(paramType, 0, 0)
 			for _, paramStr := range paramStrs {
-				elem := parseValidatorParam(paramType.Elem(), paramStr)
+				elem, err := parseValidatorParam(paramType.Elem(), paramStr)
+				if err != nil {
+					return nil, err
+				}
 				res = reflect.Append(res, reflect.ValueOf(elem))
 			}
-			return []any{res.Interface()}
+			return []any{res.Interface()}, nil
 		default:
 			if len(paramStrs) != 1 {
-				panic(fmt.Sprintf("%s expected 1 parameter, got %d", name, len(paramStrs)))
+				return nil, fmt.Errorf("%w: %s expected 1 parameter, got %d", rule.ErrInvalidParams, name, len(paramStrs))
 			}
-			return []any{parseValidatorParam(paramType, paramStrs[0])}
+			param, err := parseValidatorParam(paramType, paramStrs[0])
+			if err != nil {
+				return nil, err
+			}
+			return []any{param}, nil
 		}
 	}
 
 	if len(paramStrs) != paramTypesLen {
-		panic(fmt.Sprintf("%s expected %d parameters, got %d", name, paramTypesLen, len(paramStrs)))
+		return nil, fmt.Errorf("%w: %s expected %d parameters, got %d", rule.ErrInvalidParams, name, paramTypesLen, len(paramStrs))
 	}
 
 	params := make([]any, paramTypesLen)
 	for i, paramType := range paramTypes {
-		params[i] = parseValidatorParam(paramType, paramStrs[i])
+		param, err := parseValidatorParam(paramType, paramStrs[i])
+		if err != nil {
+			return nil, err
+		}
+		params[i] = param
 	}
 
-	return params
+	return params, nil
 }
 
-func parseValidatorParam(paramType reflect.Type, paramValue string) any {
+func parseValidatorParam(paramType reflect.Type, paramValue string) (any, error) {
+	if param, ok, err := rule.ParseTextParam(paramType, paramValue); ok {
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", rule.ErrInvalidParams, err)
+		}
+		return param, nil
+	}
+
 	switch paramType.Kind() {
 	case reflect.Bool:
 		var v bool
 		if _, err := fmt.Sscanf(paramValue, "%t", &v); err != nil {
-			panic(fmt.Sprintf("invalid bool parameter: %s", paramValue))
+			return nil, fmt.Errorf("%w: invalid bool parameter: %s", rule.ErrInvalidParams, paramValue)
 		}
-		return v
+		return v, nil
 	case reflect.Int:
 		var v int
 		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid int parameter: %s", paramValue))
+			return nil, fmt.Errorf("%w: invalid int parameter: %s", rule.ErrInvalidParams, paramValue)
 		}
-		return v
+		return v, nil
 	case reflect.Int8:
 		var v int8
 		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid int8 parameter: %s", paramValue))
+			return nil, fmt.Errorf("%w: invalid int8 parameter: %s", rule.ErrInvalidParams, paramValue)
 		}
-		return v
+		return v, nil
 	case reflect.Int16:
 		var v int16
 		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid int16 parameter: %s", paramValue))
+			return nil, fmt.Errorf("%w: invalid int16 parameter: %s", rule.ErrInvalidParams, paramValue)
 		}
-		return v
+		return v, nil
 	case reflect.Int32:
 		var v int32
 		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid int32 parameter: %s", paramValue))
+			return nil, fmt.Errorf("%w: invalid int32 parameter: %s", rule.ErrInvalidParams, paramValue)
 		}
-		return v
+		return v, nil
 	case reflect.Int64:
 		var v int64
 		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid int64 parameter: %s", paramValue))
+			return nil, fmt.Errorf("%w: invalid int64 parameter: %s", rule.ErrInvalidParams, paramValue)
 		}
-		return v
+		return v, nil
 	case reflect.Uint:
 		var v uint
 		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid uint parameter: %s", paramValue))
+			return nil, fmt.Errorf("%w: invalid uint parameter: %s", rule.ErrInvalidParams, paramValue)
 		}
-		return v
+		return v, nil
 	case reflect.Uint8:
 		var v uint8
 		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid uint8 parameter: %s", paramValue))
+			return nil, fmt.Errorf("%w: invalid uint8 parameter: %s", rule.ErrInvalidParams, paramValue)
 		}
-		return v
+		return v, nil
 	case reflect.Uint16:
 		var v uint16
 		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid uint16 parameter: %s", paramValue))
+			return nil, fmt.Errorf("%w: invalid uint16 parameter: %s", rule.ErrInvalidParams, paramValue)
 		}
-		return v
+		return v, nil
 	case reflect.Uint32:
 		var v uint32
 		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid uint32 parameter: %s", paramValue))
+			return nil, fmt.Errorf("%w: invalid uint32 parameter: %s", rule.ErrInvalidParams, paramValue)
 		}
-		return v
+		return v, nil
 	case reflect.Uint64:
 		var v uint64
 		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid uint64 parameter: %s", paramValue))
+			return nil, fmt.Errorf("%w: invalid uint64 parameter: %s", rule.ErrInvalidParams, paramValue)
 		}
-		return v
+		return v, nil
 	case reflect.Float32:
 		var v float32
 		if _, err := fmt.Sscanf(paramValue, "%f", &v); err != nil {
-			panic(fmt.Sprintf("invalid float32 parameter: %s", paramValue))
+			return nil, fmt.Errorf("%w: invalid float32 parameter: %s", rule.ErrInvalidParams, paramValue)
 		}
-		return v
+		return v, nil
 	case reflect.Float64:
 		var v float64
 		if _, err := fmt.Sscanf(paramValue, "%f", &v); err != nil {
-			panic(fmt.Sprintf("invalid float64 parameter: %s", paramValue))
+			return nil, fmt.Errorf("%w: invalid float64 parameter: %s", rule.ErrInvalidParams, paramValue)
 		}
-		return v
+		return v, nil
 	case reflect.String, reflect.Interface:
-		return paramValue
+		return paramValue, nil
 	default:
-		panic(fmt.Sprintf("unsupported parameter type: %s", paramType.Kind()))
+		return nil, fmt.Errorf("%w: unsupported parameter type: %s", rule.ErrInvalidParams, paramType.Kind())
 	}
 }
 
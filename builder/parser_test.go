@@ -0,0 +1,90 @@
+package builder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weilence/schema-validator/data"
+	"github.com/weilence/schema-validator/schema"
+	"github.com/weilence/schema-validator/tag"
+)
+
+type money struct {
+	Amount   int64
+	Currency string
+}
+
+type tagList []string
+
+func validate(t *testing.T, s schema.Schema, value any) schema.ValidationErrors {
+	ctx := schema.NewContext(s, data.New(value))
+	err := s.Validate(ctx)
+	assert.NoError(t, err)
+	return ctx.Errors()
+}
+
+func TestParse_TypeHandler(t *testing.T) {
+	type Order struct {
+		Total money `json:"total" validate:"required"`
+	}
+
+	handled := false
+	s, err := Parse(reflect.TypeFor[Order](), WithTypeHandler(reflect.TypeFor[money](), func(fieldType reflect.Type, rules []tag.Rule, cfg *ParseConfig) (schema.Schema, error) {
+		handled = true
+		validators, err := NewValidators(rules, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		fieldSchema := schema.NewField()
+		for _, v := range validators {
+			fieldSchema.AddValidator(v)
+		}
+		return fieldSchema, nil
+	}))
+	assert.NoError(t, err)
+	assert.True(t, handled)
+	assert.IsType(t, &schema.FieldSchema{}, s.Field("total"))
+
+	errs := validate(t, s, Order{})
+	assert.True(t, errs.HasFieldError("total"))
+	assert.Empty(t, validate(t, s, Order{Total: money{Amount: 1}}))
+}
+
+func TestParse_NameFunc(t *testing.T) {
+	type User struct {
+		Name     string `json:"name" validate:"required"`
+		Internal string `validate:"required"`
+	}
+
+	s, err := Parse(reflect.TypeFor[User](), WithNameFunc(func(field reflect.StructField) string {
+		if field.Tag.Get("json") == "" {
+			return ""
+		}
+		return strings.ToUpper(field.Name)
+	}))
+	assert.NoError(t, err)
+	assert.NotNil(t, s.Field("NAME"))
+	assert.Nil(t, s.Field("Internal"))
+
+	errs := validate(t, s, User{})
+	assert.True(t, errs.HasFieldError("NAME"))
+	assert.Len(t, errs, 1)
+}
+
+func TestParse_TypeDiveTag(t *testing.T) {
+	type Post struct {
+		Tags  tagList  `json:"tags" validate:"max=2|each|min=3"`
+		Lines []string `json:"lines" validate:"dive|min=3"`
+	}
+
+	s, err := Parse(reflect.TypeFor[Post](), WithTypeDiveTag(reflect.TypeFor[tagList](), "each"))
+	assert.NoError(t, err)
+
+	errs := validate(t, s, Post{Tags: tagList{"go", "rust"}, Lines: []string{"a"}})
+	assert.True(t, errs.HasFieldError("tags[0]"))
+	assert.False(t, errs.HasFieldError("tags[1]"))
+	assert.True(t, errs.HasFieldError("lines[0]"))
+}
@@ -0,0 +1,185 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/weilence/schema-validator/data"
+	"github.com/weilence/schema-validator/rule"
+	"github.com/weilence/schema-validator/schema"
+)
+
+// Wildcard is the path segment of rule maps matching every element of an array or value of a map
+const Wildcard = "*"
+
+// ErrInvalidRules is returned for rule maps with invalid paths
+var ErrInvalidRules = errors.New("invalid rules")
+
+// CompileRules compiles a flat map of paths to rule strings into a schema, such as
+//
+//	map[string]string{
+//		"user.email":  "required|email",
+//		"items":       "max=50",
+//		"items.*.qty": "required|min=1",
+//	}
+//
+// Paths follow data.ParsePath, with quoted keys for names holding dots, and * matching every
+// element of an array or value of a map. Each path becomes a nested object field, and rules
+// apply to the value at their path, so errors are reported at paths such as items[3].qty.
+// As with nested structs, a missing object only runs its own rules: add required to user
+// for a missing user to be reported. A value of the wrong shape for the paths below it, such as
+// a string for items when items.*.qty has rules, is reported as a type error at its path.
+// Rules are compiled with the registry and tag parser of the options, and every problem
+// is reported together as rule.BuildErrors with the paths of the entries
+func CompileRules(rules map[string]string, opts ...ParseOption) (schema.Schema, error) {
+	cfg := NewParseConfig(opts...)
+	c := &ruleCompiler{cfg: cfg}
+
+	// Paths are sorted so that fields and errors come in a stable order
+	paths := make([]string, 0, len(rules))
+	for path := range rules {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	root := &ruleNode{}
+	for _, path := range paths {
+		segments, err := data.ParsePath(path)
+		if err != nil {
+			c.errorf(path, "%w", err)
+			continue
+		}
+
+		node := root
+		for _, seg := range segments {
+			if seg.IsIndex {
+				c.errorf(path, "%w: index %s can't be used in rule paths, use %s", ErrInvalidRules, seg, Wildcard)
+				node = nil
+				break
+			}
+			node = node.child(seg.Name)
+		}
+
+		if node != nil {
+			node.path, node.rules = path, append(node.rules, rules[path])
+		}
+	}
+
+	s := c.compile(root, "")
+	if len(c.errs) > 0 {
+		return nil, c.errs
+	}
+
+	return s, nil
+}
+
+// ruleNode is a node of the tree of rule map paths
+type ruleNode struct {
+	path  string // path of the rule map entry, empty for nodes without rules
+	rules []string
+
+	fields map[string]*ruleNode
+	order  []string // field names in order of first use
+}
+
+func (n *ruleNode) child(name string) *ruleNode {
+	if child, ok := n.fields[name]; ok {
+		return child
+	}
+
+	if n.fields == nil {
+		n.fields = make(map[string]*ruleNode)
+	}
+
+	child := &ruleNode{}
+	n.fields[name] = child
+	n.order = append(n.order, name)
+	return child
+}
+
+// ruleCompiler holds the state of a single CompileRules call
+type ruleCompiler struct {
+	cfg  *ParseConfig
+	errs rule.BuildErrors
+}
+
+func (c *ruleCompiler) errorf(path string, format string, args ...any) {
+	c.errs = append(c.errs, rule.BuildError{Path: path, Err: fmt.Errorf(format, args...)})
+}
+
+// compile compiles a node into a field, object or, for wildcards, element schema
+func (c *ruleCompiler) compile(n *ruleNode, path string) schema.Schema {
+	var s schema.Schema
+	switch element, ok := n.fields[Wildcard]; {
+	case ok && len(n.fields) > 1:
+		c.errorf(path, "%w: %s can't be mixed with field names", ErrInvalidRules, Wildcard)
+		s = schema.NewField()
+	case ok:
+		s = each(c.compile(element, join(path, Wildcard)), c.shape(path, "array", "null"))
+	case len(n.fields) > 0:
+		obj := schema.NewObject()
+		if v := c.shape(path, "object", "null"); v != nil {
+			obj.AddValidator(v)
+		}
+		for _, name := range n.order {
+			obj.AddField(name, c.compile(n.fields[name], join(path, name)))
+		}
+		s = obj
+	default:
+		s = schema.NewField()
+	}
+
+	for _, rules := range n.rules {
+		validators, err := NewValidators(c.cfg.ParseTag(rules), c.cfg)
+		var buildErrs rule.BuildErrors
+		if errors.As(err, &buildErrs) {
+			for _, buildErr := range buildErrs {
+				buildErr.Path = n.path
+				c.errs = append(c.errs, buildErr)
+			}
+			continue
+		}
+
+		for _, v := range validators {
+			s.AddValidator(v)
+		}
+	}
+
+	return s
+}
+
+// shape returns a type rule checking that the value of a node with nested rules has the JSON
+// types, so that a payload of the wrong shape is reported at path rather than aborting validation
+// Types include null for missing values to only run the rules of the node, such as required
+func (c *ruleCompiler) shape(path string, types ...string) schema.Validator {
+	v, err := c.cfg.Registry.NewValidatorE("type", types)
+	if err != nil {
+		c.errorf(path, "%w", err)
+		return nil
+	}
+
+	return v
+}
+
+// each returns a schema validating every element of an array or value of a map against element
+// Maps of any type are iterated as maps and other values as arrays, checked by shape if not nil
+func each(element schema.Schema, shape schema.Validator) schema.Schema {
+	array := schema.NewArray(element)
+	if shape != nil {
+		array.AddValidator(shape)
+	}
+
+	return schema.NewTypeUnion().
+		AddVariant(schema.KindKey(reflect.Map), schema.NewMap(nil, element)).
+		SetFallback(array)
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
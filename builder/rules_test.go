@@ -0,0 +1,125 @@
+package builder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weilence/schema-validator/rule"
+)
+
+func TestCompileRules(t *testing.T) {
+	s, err := CompileRules(map[string]string{
+		"user":                     "required",
+		"user.email":               "required|email",
+		"items":                    "required|max=2",
+		"items.*.qty":              "required|min=1",
+		"items.*.tags.*":           "max=3",
+		"labels.*":                 "max=5",
+		`meta["app.io/name"]`:      "required",
+		"limits.max":               "required",
+		"orders.*.total":           "ltefield=$root.limits.max",
+		"orders.*.lines.*.comment": "max=10",
+	})
+	assert.NoError(t, err)
+
+	valid := map[string]any{
+		"user":   map[string]any{"email": "ann@example.com"},
+		"items":  []any{map[string]any{"qty": 1, "tags": []any{"a"}}},
+		"labels": map[string]any{"env": "prod"},
+		"meta":   map[string]any{"app.io/name": "web"},
+		"limits": map[string]any{"max": 10},
+		"orders": map[string]any{"a": map[string]any{"total": 10}},
+	}
+	assert.Empty(t, validate(t, s, valid))
+
+	invalid := map[string]any{
+		"user": map[string]any{"email": "nope"},
+		"items": []any{
+			map[string]any{"qty": 1},
+			map[string]any{"qty": -1, "tags": []any{"abcd"}},
+			map[string]any{},
+		},
+		"labels": map[string]any{"env": "production"},
+		"meta":   map[string]any{},
+		"limits": map[string]any{"max": 10},
+		"orders": []any{map[string]any{"total": 11, "lines": []any{map[string]any{"comment": "far too long"}}}},
+	}
+
+	var got []string
+	for _, e := range validate(t, s, invalid) {
+		got = append(got, e.Path+": "+e.Code)
+	}
+	assert.Equal(t, []string{
+		"items: max",
+		"items[1].qty: min",
+		"items[1].tags[0]: max",
+		"items[2].qty: required",
+		"items[2].qty: min",
+		"labels[env]: max",
+		"meta.app.io/name: required",
+		"orders[0].lines[0].comment: max",
+		"orders[0].total: ltefield",
+		"user.email: email",
+	}, got)
+
+	// A missing parent only runs its own rules
+	got = nil
+	for _, e := range validate(t, s, map[string]any{"limits": map[string]any{"max": 1}}) {
+		got = append(got, e.Path+": "+e.Code)
+	}
+	assert.Equal(t, []string{"items: required", "user: required"}, got)
+
+	// Maps of any type are iterated as maps
+	got = nil
+	typed := map[string]any{
+		"user":   map[string]any{"email": "ann@example.com"},
+		"items":  []any{},
+		"labels": map[string]string{"env": "production"},
+		"meta":   map[string]string{"app.io/name": "web"},
+		"limits": map[string]int{"max": 1},
+	}
+	for _, e := range validate(t, s, typed) {
+		got = append(got, e.Path+": "+e.Code)
+	}
+	assert.Equal(t, []string{"labels[env]: max"}, got)
+
+	// Payloads of the wrong shape are reported at the path of the nested rules
+	got = nil
+	shaped := map[string]any{
+		"user":   5,
+		"items":  "abc",
+		"labels": "env",
+		"limits": map[string]any{"max": 1},
+	}
+	for _, e := range validate(t, s, shaped) {
+		got = append(got, e.Path+": "+e.Code)
+	}
+	assert.Equal(t, []string{"items: max", "items: type", "labels: type", "user: type"}, got)
+}
+
+func TestCompileRules_Errors(t *testing.T) {
+	_, err := CompileRules(map[string]string{
+		"name":      "required|nosuchrule",
+		"items.*":   "min=1",
+		"items.qty": "min=1",
+		"tags[0]":   "max=3",
+		"a..b":      "required",
+	})
+
+	var errs rule.BuildErrors
+	assert.True(t, errors.As(err, &errs))
+
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{
+		`a..b: invalid path "a..b": empty name at position 2`,
+		`tags[0]: invalid rules: index [0] can't be used in rule paths, use *`,
+		`items: invalid rules: * can't be mixed with field names`,
+		`name: rule "nosuchrule": validator not found in registry`,
+	}, got)
+	assert.ErrorIs(t, err, rule.ErrNotFound)
+	assert.ErrorIs(t, err, ErrInvalidRules)
+}
@@ -2,7 +2,6 @@ package data
 
 import (
 	"reflect"
-	"strings"
 )
 
 // DataKind represents the kind of data
@@ -17,6 +16,7 @@ const (
 // Accessor provides unified interface for accessing different data types
 type Accessor interface {
 	GetField(name string) (Accessor, error)
+	// GetValue returns the value at a path in the grammar of ParsePath, the accessor's own value for ""
 	GetValue(path string) (*Value, error)
 	Raw() any
 }
@@ -57,8 +57,3 @@ type ObjectAccessor interface {
 	Accessor
 	Accessors() []ObjectAccessor
 }
-
-func cutPath(path string) (string, string) {
-	before, after, _ := strings.Cut(path, ".")
-	return before, after
-}
@@ -41,3 +41,95 @@ func TestAccessor_Combinations_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []PathSegment
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"a.b", []PathSegment{{Name: "a"}, {Name: "b"}}, false},
+		{"items[0].price", []PathSegment{{Name: "items"}, {Index: 0, IsIndex: true}, {Name: "price"}}, false},
+		{"items.[1]", []PathSegment{{Name: "items"}, {Index: 1, IsIndex: true}}, false},
+		{"m[1][2]", []PathSegment{{Name: "m"}, {Index: 1, IsIndex: true}, {Index: 2, IsIndex: true}}, false},
+		{`labels["app.kubernetes.io/name"]`, []PathSegment{{Name: "labels"}, {Name: "app.kubernetes.io/name"}}, false},
+		{`labels['it\'s']`, []PathSegment{{Name: "labels"}, {Name: "it's"}}, false},
+		{"items[*].sku", []PathSegment{{Name: "items"}, {Name: "*"}, {Name: "sku"}}, false},
+		{"labels[env]", []PathSegment{{Name: "labels"}, {Name: "env"}}, false},
+		{"labels[app.io/tier].$key", []PathSegment{{Name: "labels"}, {Name: "app.io/tier"}, {Name: "$key"}}, false},
+		{"a[-1]", []PathSegment{{Name: "a"}, {Name: "-1"}}, false},
+		{"a..b", nil, true},
+		{".a", nil, true},
+		{"a.", nil, true},
+		{"a[", nil, true},
+		{"a[]", nil, true},
+		{"a[x'y]", nil, true},
+		{"a[x[y]", nil, true},
+		{`a["b`, nil, true},
+		{`a["b"`, nil, true},
+		{"a[0]b", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := ParsePath(tt.path)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidPath)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAccessor_Paths(t *testing.T) {
+	type Limits struct{ MaxAmount int }
+	type Order struct {
+		Limits  *Limits
+		Items   []map[string]int
+		Labels  map[string]string
+		ByID    map[int]string
+		Missing *Limits
+	}
+
+	acc := New(Order{
+		Limits: &Limits{MaxAmount: 100},
+		Items:  []map[string]int{{"amount": 5}},
+		Labels: map[string]string{"app.io/name": "web"},
+		ByID:   map[int]string{7: "seven"},
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		want     string
+		notFound bool
+	}{
+		{"pointer struct", "Limits.MaxAmount", "100", false},
+		{"index then key", "Items[0].amount", "5", false},
+		{"quoted key", `Labels["app.io/name"]`, "web", false},
+		{"int map key", "ByID[7]", "seven", false},
+		{"int map key by name", "ByID.7", "seven", false},
+		{"missing key", "Items[0].other", "", true},
+		{"index out of bounds", "Items[3].amount", "", true},
+		{"nil pointer", "Missing.MaxAmount", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := acc.GetValue(tt.path)
+			if tt.notFound {
+				assert.ErrorIs(t, err, ErrKeyNotFound)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, v.String())
+		})
+	}
+
+	_, err := acc.GetValue("ByID.x")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrKeyNotFound)
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
 type ArrayAccessor struct {
@@ -22,19 +24,14 @@ func (s *ArrayAccessor) deref() reflect.Value {
 	return v
 }
 
+// GetField returns the element at an index written as [n] or n
 func (s *ArrayAccessor) GetField(name string) (Accessor, error) {
-	var idx int
-	n, err := fmt.Sscanf(name, "[%d]", &idx)
-	if err != nil || n != 1 {
-		return nil, errors.New("invalid array index in scan: " + name)
-	}
-
-	elemAcc, err := s.GetIndex(idx)
+	idx, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "["), "]"))
 	if err != nil {
-		return nil, fmt.Errorf("index %d out of bounds", idx)
+		return nil, errors.New("invalid array index in scan: " + name)
 	}
 
-	return elemAcc, nil
+	return s.GetIndex(idx)
 }
 
 func (s *ArrayAccessor) Raw() any {
@@ -46,19 +43,13 @@ func (s *ArrayAccessor) GetValue(path string) (*Value, error) {
 		return NewValueAccessor(s.value), nil
 	}
 
-	part, nextPath := cutPath(path)
-	elemAcc, err := s.GetField(part)
-	if err != nil {
-		return nil, err
-	}
-
-	return elemAcc.GetValue(nextPath)
+	return getValue(s, path)
 }
 
 func (s *ArrayAccessor) GetIndex(idx int) (Accessor, error) {
 	v := s.deref()
 	if idx < 0 || idx >= v.Len() {
-		return nil, fmt.Errorf("index %d out of bounds", idx)
+		return nil, fmt.Errorf("%w: index %d out of bounds", ErrKeyNotFound, idx)
 	}
 
 	elem := v.Index(idx)
@@ -36,8 +36,8 @@ func TestArrayAccessor_Errors_TableDriven(t *testing.T) {
 		wantErr string
 	}{
 		{"plain invalid", "x", "invalid array index in scan: x"},
-		{"scan invalid", "[x]", "invalid array index in scan: [x]"},
-		{"oob", "[5]", "index 5 out of bounds"},
+		{"bracket invalid", "[x]", "invalid array index in scan: x"},
+		{"oob", "[5]", "key not found: index 5 out of bounds"},
 	}
 
 	for _, tt := range tests {
@@ -1,19 +1,27 @@
 package data
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"slices"
+	"strings"
 )
 
-type mapAccessor struct {
+// ErrKeyNotFound is returned when a map has no entry for the requested key, an index is out of
+// bounds, or a path goes through a nil value
+var ErrKeyNotFound = errors.New("key not found")
+
+// MapAccessor provides access to map entries
+type MapAccessor struct {
 	value reflect.Value
 }
 
-func NewMapAccessor(v reflect.Value) *mapAccessor {
-	return &mapAccessor{value: v}
+func NewMapAccessor(v reflect.Value) *MapAccessor {
+	return &MapAccessor{value: v}
 }
 
-func (m *mapAccessor) deref() reflect.Value {
+func (m *MapAccessor) deref() reflect.Value {
 	v := m.value
 	for v.Kind() == reflect.Pointer {
 		v = v.Elem()
@@ -21,38 +29,56 @@ func (m *mapAccessor) deref() reflect.Value {
 	return v
 }
 
-func (m *mapAccessor) Raw() any {
+func (m *MapAccessor) Raw() any {
 	return m.value.Interface()
 }
 
-func (m *mapAccessor) GetValue(path string) (*Value, error) {
+func (m *MapAccessor) GetValue(path string) (*Value, error) {
 	if path == "" {
 		return NewValueAccessor(m.value), nil
 	}
 
-	fieldName, nextPath := cutPath(path)
+	return getValue(m, path)
+}
 
-	fieldAcc, err := m.GetField(fieldName)
+// GetField returns the entry for a key, converting name to the key type of the map
+func (m *MapAccessor) GetField(name string) (Accessor, error) {
+	v := m.deref()
+	keyVal, err := mapKey(v.Type().Key(), name)
 	if err != nil {
 		return nil, err
 	}
 
-	return fieldAcc.GetValue(nextPath)
-}
-
-func (m *mapAccessor) GetField(name string) (Accessor, error) {
-	v := m.deref()
-	keyVal := reflect.ValueOf(name)
-
 	val := v.MapIndex(keyVal)
 	if !val.IsValid() {
-		return nil, fmt.Errorf("key %s not found in map", name)
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, name)
 	}
 
 	return NewAccessor(val), nil
 }
 
-func (m *mapAccessor) Accessors() []ObjectAccessor {
+func (m *MapAccessor) Accessors() []ObjectAccessor {
 	accessors := []ObjectAccessor{m}
 	return accessors
 }
+
+func (m *MapAccessor) Len() int {
+	return m.deref().Len()
+}
+
+// Iterate calls fn for every entry, ordered by the string form of the keys
+func (m *MapAccessor) Iterate(fn func(key *Value, elem Accessor) error) error {
+	v := m.deref()
+	keys := v.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int {
+		return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+	})
+
+	for _, key := range keys {
+		if err := fn(NewValueAccessor(key), NewAccessor(v.MapIndex(key))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -32,3 +32,35 @@ func TestMapAccessor_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+func TestMapAccessor_Iterate(t *testing.T) {
+	acc := New(map[string]int{"b": 2, "c": 3, "a": 1}).(*MapAccessor)
+	assert.Equal(t, 3, acc.Len())
+
+	var keys []string
+	sum := 0
+	err := acc.Iterate(func(key *Value, elem Accessor) error {
+		keys = append(keys, key.String())
+		v, err := elem.GetValue("")
+		if err != nil {
+			return err
+		}
+		sum += int(v.Int())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, keys)
+	assert.Equal(t, 6, sum)
+}
+
+func TestMapAccessor_MissingKey(t *testing.T) {
+	acc := New(map[string]any{"a": nil})
+
+	_, err := acc.GetField("nope")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// A present key holding nil is not missing
+	field, err := acc.GetField("a")
+	assert.NoError(t, err)
+	assert.Nil(t, field.Raw())
+}
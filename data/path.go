@@ -0,0 +1,217 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// ErrInvalidPath is returned for paths that don't follow the path grammar
+var ErrInvalidPath = errors.New("invalid path")
+
+// PathSegment is a step of a path, a field name or map key, or an index
+type PathSegment struct {
+	Name    string
+	Index   int
+	IsIndex bool
+}
+
+// String returns the segment as written in a path
+func (s PathSegment) String() string {
+	if s.IsIndex {
+		return "[" + strconv.Itoa(s.Index) + "]"
+	}
+
+	if s.Name == "" || strings.ContainsAny(s.Name, ".[]\"'\\") {
+		return "[" + strconv.Quote(s.Name) + "]"
+	}
+
+	return s.Name
+}
+
+// ParsePath splits a path into its segments
+// Names are separated by dots, [n] selects an element of an array or the key n of a map,
+// [key] selects a map key as written in validation errors, and ["key"] or ['key'] selects
+// a map key that may also hold brackets or quotes, e.g.
+//
+//	items[0].price
+//	items.[0].price
+//	labels[env]
+//	labels["app.kubernetes.io/name"]
+//	matrix[1][2]
+//
+// [*] is the same as the name *, which rule maps and partial validation use to match
+// every element of an array or value of a map
+func ParsePath(path string) ([]PathSegment, error) {
+	var segments []PathSegment
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '[':
+			seg, n, err := parseBracket(path[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: %w", ErrInvalidPath, path, err)
+			}
+			segments = append(segments, seg)
+			i += n
+		case '.':
+			if i == 0 || i+1 == len(path) || path[i+1] == '.' {
+				return nil, fmt.Errorf("%w %q: empty name at position %d", ErrInvalidPath, path, i+1)
+			}
+			i++
+		default:
+			if i > 0 && path[i-1] != '.' {
+				return nil, fmt.Errorf("%w %q: expected . or [ at position %d", ErrInvalidPath, path, i+1)
+			}
+
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			if strings.ContainsAny(path[i:i+end], "]\"'") {
+				return nil, fmt.Errorf("%w %q: unexpected character in %q", ErrInvalidPath, path, path[i:i+end])
+			}
+
+			segments = append(segments, PathSegment{Name: path[i : i+end]})
+			i += end
+		}
+	}
+
+	return segments, nil
+}
+
+// parseBracket parses a [n], [key], ["key"] or ['key'] segment at the start of s, returning its length
+func parseBracket(s string) (PathSegment, int, error) {
+	if strings.HasPrefix(s, "[*]") {
+		return PathSegment{Name: "*"}, 3, nil
+	}
+
+	if len(s) > 1 && (s[1] == '"' || s[1] == '\'') {
+		quote := s[1]
+		var sb strings.Builder
+		for i := 2; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+				if i < len(s) {
+					sb.WriteByte(s[i])
+				}
+			case quote:
+				if i+1 >= len(s) || s[i+1] != ']' {
+					return PathSegment{}, 0, errors.New("expected ] after quoted key")
+				}
+				return PathSegment{Name: sb.String()}, i + 2, nil
+			default:
+				sb.WriteByte(s[i])
+			}
+		}
+
+		return PathSegment{}, 0, errors.New("unterminated quoted key")
+	}
+
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return PathSegment{}, 0, errors.New("unclosed [")
+	}
+
+	if idx, err := strconv.Atoi(s[1:end]); err == nil && idx >= 0 {
+		return PathSegment{Index: idx, IsIndex: true}, end + 1, nil
+	}
+
+	key := s[1:end]
+	if key == "" || strings.ContainsAny(key, "[\"'\\") {
+		return PathSegment{}, 0, fmt.Errorf("invalid key %q, keys holding brackets or quotes must be quoted", key)
+	}
+
+	return PathSegment{Name: key}, end + 1, nil
+}
+
+// GetPath returns the accessor at a parsed path
+// Missing map keys, indexes out of range and nil values along the path return ErrKeyNotFound
+func GetPath(acc Accessor, segments []PathSegment) (Accessor, error) {
+	for _, seg := range segments {
+		next, err := step(acc, seg)
+		if err != nil {
+			return nil, err
+		}
+		acc = next
+	}
+
+	return acc, nil
+}
+
+// getValue implements Accessor.GetValue with the path grammar of ParsePath
+func getValue(acc Accessor, path string) (*Value, error) {
+	segments, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	acc, err = GetPath(acc, segments)
+	if err != nil {
+		return nil, err
+	}
+
+	if v, ok := acc.(*Value); ok {
+		return v, nil
+	}
+
+	return acc.GetValue("")
+}
+
+func step(acc Accessor, seg PathSegment) (Accessor, error) {
+	switch a := acc.(type) {
+	case *ArrayAccessor:
+		if !seg.IsIndex {
+			return a.GetField(seg.Name)
+		}
+		return a.GetIndex(seg.Index)
+	case *MapAccessor:
+		name := seg.Name
+		if seg.IsIndex {
+			name = strconv.Itoa(seg.Index)
+		}
+		return a.GetField(name)
+	case *Value:
+		if a.IsNilOrZero() && (a.Kind() == reflect.Invalid || a.Kind() == reflect.Pointer || a.Kind() == reflect.Interface) {
+			return nil, fmt.Errorf("%w: %s of nil value", ErrKeyNotFound, seg)
+		}
+		return nil, fmt.Errorf("cannot get %s of primitive value", seg)
+	default:
+		if seg.IsIndex {
+			return nil, fmt.Errorf("cannot index %T with %s", acc, seg)
+		}
+		return acc.GetField(seg.Name)
+	}
+}
+
+// mapKey converts a path name into a key of a map with keys of type keyType
+func mapKey(keyType reflect.Type, name string) (reflect.Value, error) {
+	var key any
+	var err error
+	switch keyType.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(name).Convert(keyType), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		key, err = cast.ToInt64E(name)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		key, err = cast.ToUint64E(name)
+	case reflect.Float32, reflect.Float64:
+		key, err = cast.ToFloat64E(name)
+	case reflect.Bool:
+		key, err = cast.ToBoolE(name)
+	case reflect.Interface:
+		return reflect.ValueOf(name), nil
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported map key type %s", keyType)
+	}
+
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("invalid %s map key %q", keyType, name)
+	}
+
+	return reflect.ValueOf(key).Convert(keyType), nil
+}
@@ -1,10 +1,14 @@
 package data
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 )
 
+// ErrFieldNotFound is returned when a struct has no field of the given name
+var ErrFieldNotFound = errors.New("field not found")
+
 type structAccessor struct {
 	value reflect.Value
 
@@ -60,14 +64,7 @@ func (s *structAccessor) GetValue(path string) (*Value, error) {
 		return NewValueAccessor(s.value), nil
 	}
 
-	fieldName, nextPath := cutPath(path)
-
-	fieldAcc, err := s.GetField(fieldName)
-	if err != nil {
-		return nil, err
-	}
-
-	return fieldAcc.GetValue(nextPath)
+	return getValue(s, path)
 }
 
 func (s *structAccessor) GetField(name string) (Accessor, error) {
@@ -83,7 +80,7 @@ func (s *structAccessor) GetField(name string) (Accessor, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("field %s not found", name)
+	return nil, fmt.Errorf("%w: %s", ErrFieldNotFound, name)
 }
 
 func (s *structAccessor) Accessors() []ObjectAccessor {
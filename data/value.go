@@ -28,7 +28,12 @@ func (p *Value) GetField(name string) (Accessor, error) {
 	return p, nil
 }
 
+// Raw returns the underlying value, or nil for an absent value
 func (p *Value) Raw() any {
+	if !p.rval.IsValid() {
+		return nil
+	}
+
 	return p.rval.Interface()
 }
 
@@ -119,6 +124,10 @@ func (p *Value) IsNilOrZero() bool {
 
 func (p *Value) Any() any {
 	val := p.rval
+	if !val.IsValid() {
+		return nil
+	}
+
 	for val.Kind() == reflect.Pointer {
 		if val.IsNil() {
 			return nil
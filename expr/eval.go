@@ -0,0 +1,474 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// kind is the type of a value, kindAny for paths whose type is only known when evaluated
+type kind int
+
+const (
+	kindAny kind = iota
+	kindNull
+	kindBool
+	kindNumber
+	kindString
+	kindTime
+	kindList
+	kindObject
+)
+
+func (k kind) String() string {
+	return [...]string{"any", "null", "bool", "number", "string", "time", "list", "object"}[k]
+}
+
+// node is a node of the syntax tree
+// check returns its type, or an error if an operator or function can't take its operands,
+// and eval computes its value, one of nil, bool, float64, string, time.Time, []any or object
+type node interface {
+	check() (kind, error)
+	eval(env Env) (any, error)
+}
+
+// object is the value of maps and structs, which expressions can only take the length of
+type object struct {
+	len int
+}
+
+type literal struct {
+	value any
+}
+
+func (n *literal) check() (kind, error) {
+	return kindOf(n.value), nil
+}
+
+func (n *literal) eval(Env) (any, error) {
+	return n.value, nil
+}
+
+type ref struct {
+	path string
+	pos  int
+}
+
+func (n *ref) check() (kind, error) {
+	return kindAny, nil
+}
+
+func (n *ref) eval(env Env) (any, error) {
+	v, err := env.Lookup(n.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return normalize(reflect.ValueOf(v)), nil
+}
+
+type unary struct {
+	op      string
+	pos     int
+	operand node
+}
+
+func (n *unary) check() (kind, error) {
+	t, err := n.operand.check()
+	if err != nil {
+		return kindAny, err
+	}
+
+	want := kindNumber
+	if n.op == "!" {
+		want = kindBool
+	}
+
+	if !accepts(t, want) {
+		return kindAny, errorAt(n.pos, ErrType, "%s can't be applied to a %s", n.op, t)
+	}
+
+	return want, nil
+}
+
+func (n *unary) eval(env Env) (any, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		if n.op == "!" {
+			return true, nil
+		}
+		return nil, nil
+	case bool:
+		if n.op == "!" {
+			return !v, nil
+		}
+	case float64:
+		if n.op == "-" {
+			return -v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s can't be applied to a %s", ErrType, n.op, kindOf(v))
+}
+
+type binary struct {
+	op          string
+	pos         int
+	left, right node
+}
+
+func (n *binary) check() (kind, error) {
+	l, err := n.left.check()
+	if err != nil {
+		return kindAny, err
+	}
+
+	r, err := n.right.check()
+	if err != nil {
+		return kindAny, err
+	}
+
+	mismatch := func() (kind, error) {
+		return kindAny, errorAt(n.pos, ErrType, "%s can't be applied to a %s and a %s", n.op, l, r)
+	}
+
+	switch n.op {
+	case "&&", "||":
+		if !accepts(l, kindBool) || !accepts(r, kindBool) {
+			return mismatch()
+		}
+		return kindBool, nil
+	case "==", "!=":
+		if l != kindAny && r != kindAny && l != kindNull && r != kindNull && l != r {
+			return mismatch()
+		}
+		return kindBool, nil
+	case "<", "<=", ">", ">=":
+		t := l
+		if t == kindAny {
+			t = r
+		}
+		if t != kindAny && t != kindNumber && t != kindString && t != kindTime || !accepts(l, t) || !accepts(r, t) {
+			return mismatch()
+		}
+		return kindBool, nil
+	case "+":
+		if (l == kindString || r == kindString) && accepts(l, kindString) && accepts(r, kindString) {
+			return kindString, nil
+		}
+		if !accepts(l, kindNumber) || !accepts(r, kindNumber) {
+			return mismatch()
+		}
+		if l != kindNumber && r != kindNumber {
+			// Both could be strings
+			return kindAny, nil
+		}
+		return kindNumber, nil
+	default:
+		if !accepts(l, kindNumber) || !accepts(r, kindNumber) {
+			return mismatch()
+		}
+		return kindNumber, nil
+	}
+}
+
+func (n *binary) eval(env Env) (any, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// && and || only evaluate their right operand when needed, with null as false
+	switch n.op {
+	case "&&", "||":
+		lb, err := truth(n.op, l)
+		if err != nil {
+			return nil, err
+		}
+		if lb == (n.op == "||") {
+			return lb, nil
+		}
+
+		r, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		return truth(n.op, r)
+	}
+
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	}
+
+	// Other operators on null are null, so a missing value fails a comparison
+	if l == nil || r == nil {
+		if n.op == "<" || n.op == "<=" || n.op == ">" || n.op == ">=" {
+			return false, nil
+		}
+		return nil, nil
+	}
+
+	mismatch := fmt.Errorf("%w: %s can't be applied to a %s and a %s", ErrType, n.op, kindOf(l), kindOf(r))
+	switch n.op {
+	case "<", "<=", ">", ">=":
+		c, ok := order(l, r)
+		if !ok {
+			return nil, mismatch
+		}
+
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}
+
+	if ls, ok := l.(string); ok && n.op == "+" {
+		if rs, ok := r.(string); ok {
+			return ls + rs, nil
+		}
+	}
+
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return nil, mismatch
+	}
+
+	switch n.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return lf / rf, nil
+	default:
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(lf, rf), nil
+	}
+}
+
+type call struct {
+	name string
+	pos  int
+	fn   function
+	args []node
+}
+
+func (n *call) check() (kind, error) {
+	if len(n.args) != len(n.fn.params) {
+		return kindAny, errorAt(n.pos, ErrType, "%s takes %d arguments, got %d", n.name, len(n.fn.params), len(n.args))
+	}
+
+	for i, arg := range n.args {
+		t, err := arg.check()
+		if err != nil {
+			return kindAny, err
+		}
+
+		if !accepts(t, n.fn.params[i]...) {
+			return kindAny, errorAt(n.pos, ErrType, "%s can't take a %s", n.name, t)
+		}
+	}
+
+	return n.fn.result, nil
+}
+
+func (n *call) eval(env Env) (any, error) {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+
+		if v != nil && !accepts(kindOf(v), n.fn.params[i]...) {
+			return nil, fmt.Errorf("%w: %s can't take a %s", ErrType, n.name, kindOf(v))
+		}
+		args[i] = v
+	}
+
+	return n.fn.call(args)
+}
+
+// walk calls fn for n and every node below it
+func walk(n node, fn func(node)) {
+	fn(n)
+	switch n := n.(type) {
+	case *unary:
+		walk(n.operand, fn)
+	case *binary:
+		walk(n.left, fn)
+		walk(n.right, fn)
+	case *call:
+		for _, arg := range n.args {
+			walk(arg, fn)
+		}
+	}
+}
+
+// accepts reports whether a value of type t can be used where one of want is expected
+func accepts(t kind, want ...kind) bool {
+	if t == kindAny || t == kindNull {
+		return true
+	}
+
+	for _, w := range want {
+		if w == kindAny || w == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+func kindOf(v any) kind {
+	switch v.(type) {
+	case nil:
+		return kindNull
+	case bool:
+		return kindBool
+	case float64:
+		return kindNumber
+	case string:
+		return kindString
+	case time.Time:
+		return kindTime
+	case []any:
+		return kindList
+	default:
+		return kindObject
+	}
+}
+
+// normalize converts a Go value into a value of an expression
+func normalize(rv reflect.Value) any {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	if !rv.IsValid() {
+		return nil
+	}
+
+	if t, ok := rv.Interface().(time.Time); ok {
+		return t
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+
+		list := make([]any, rv.Len())
+		for i := range list {
+			list[i] = normalize(rv.Index(i))
+		}
+		return list
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil
+		}
+		return object{len: rv.Len()}
+	case reflect.Struct:
+		return object{len: rv.NumField()}
+	default:
+		return object{}
+	}
+}
+
+func truth(op string, v any) (bool, error) {
+	switch v := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	default:
+		return false, fmt.Errorf("%w: %s can't be applied to a %s", ErrType, op, kindOf(v))
+	}
+}
+
+func equal(l, r any) bool {
+	if lt, ok := l.(time.Time); ok {
+		rt, ok := r.(time.Time)
+		return ok && lt.Equal(rt)
+	}
+
+	if kindOf(l) != kindOf(r) || kindOf(l) == kindList || kindOf(l) == kindObject {
+		return l == nil && r == nil
+	}
+
+	return l == r
+}
+
+// order compares two numbers, strings or times
+func order(l, r any) (int, bool) {
+	switch l := l.(type) {
+	case float64:
+		if r, ok := r.(float64); ok {
+			switch {
+			case l < r:
+				return -1, true
+			case l > r:
+				return 1, true
+			}
+			return 0, true
+		}
+	case string:
+		if r, ok := r.(string); ok {
+			switch {
+			case l < r:
+				return -1, true
+			case l > r:
+				return 1, true
+			}
+			return 0, true
+		}
+	case time.Time:
+		if r, ok := r.(time.Time); ok {
+			return l.Compare(r), true
+		}
+	}
+
+	return 0, false
+}
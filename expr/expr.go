@@ -0,0 +1,167 @@
+// Package expr implements the small expression language of the expr rule, such as
+//
+//	start < end && len(items) <= maxItems
+//
+// Expressions have number, string and bool literals, null, the arithmetic operators
+// + - * / %, comparisons, && || and !, and the functions len, sum, now, lower, upper,
+// trim, contains, startsWith and endsWith. Other names are paths read from an Env.
+// Expressions are parsed and type-checked once by Parse and can't call anything else.
+package expr
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrSyntax is returned for expressions that can't be parsed
+	ErrSyntax = errors.New("syntax error")
+
+	// ErrType is returned for expressions using operators or functions with values of the wrong type
+	ErrType = errors.New("type error")
+)
+
+// Error is a problem found in an expression, at a byte offset of its source
+type Error struct {
+	Source string
+	Pos    int
+	Err    error
+}
+
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// Error implements the error interface
+func (e Error) Error() string {
+	return fmt.Sprintf("%v at position %d of %q", e.Err, e.Pos+1, e.Source)
+}
+
+// posError is an error at a byte offset of the source
+type posError struct {
+	pos int
+	err error
+}
+
+func (e posError) Unwrap() error {
+	return e.err
+}
+
+func (e posError) Error() string {
+	return e.err.Error()
+}
+
+func errorAt(pos int, err error, format string, args ...any) error {
+	return posError{pos: pos, err: fmt.Errorf("%w: "+format, append([]any{err}, args...)...)}
+}
+
+func newError(source string, err error) error {
+	var at posError
+	if errors.As(err, &at) {
+		return Error{Source: source, Pos: at.pos, Err: at.err}
+	}
+
+	return Error{Source: source, Err: err}
+}
+
+// Env provides the values of the paths used in an expression
+type Env interface {
+	// Lookup returns the value at path, nil for missing values
+	Lookup(path string) (any, error)
+}
+
+// EnvFunc is an Env calling a function
+type EnvFunc func(path string) (any, error)
+
+func (f EnvFunc) Lookup(path string) (any, error) {
+	return f(path)
+}
+
+// Expr is a parsed and type-checked expression
+type Expr struct {
+	source string
+	root   node
+}
+
+// Parse parses and type-checks an expression, which must have a boolean result unless
+// it depends on paths, whose types are only known when it is evaluated
+func Parse(source string) (*Expr, error) {
+	p := &parser{lexer: lexer{src: source}}
+	root, err := p.parse()
+	if err != nil {
+		return nil, newError(source, err)
+	}
+
+	if t, err := root.check(); err != nil {
+		return nil, newError(source, err)
+	} else if t != kindBool && t != kindAny {
+		return nil, Error{Source: source, Pos: 0, Err: fmt.Errorf("%w: expression must be a bool, got %s", ErrType, t)}
+	}
+
+	return &Expr{source: source, root: root}, nil
+}
+
+// MustParse is like Parse but panics if the expression is invalid
+func MustParse(source string) *Expr {
+	e, err := Parse(source)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return e
+}
+
+// String returns the source of the expression
+func (e *Expr) String() string {
+	return e.source
+}
+
+// MarshalText returns the source of the expression
+func (e *Expr) MarshalText() ([]byte, error) {
+	return []byte(e.source), nil
+}
+
+// UnmarshalText parses an expression, so expressions can be rule params
+func (e *Expr) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+
+	*e = *parsed
+	return nil
+}
+
+// Paths returns the paths used by the expression in the order they appear
+func (e *Expr) Paths() []string {
+	var paths []string
+	walk(e.root, func(n node) {
+		if r, ok := n.(*ref); ok {
+			paths = append(paths, r.path)
+		}
+	})
+
+	return paths
+}
+
+// Eval evaluates the expression, returning nil, a bool, a float64, a string, a time.Time or a []any
+func (e *Expr) Eval(env Env) (any, error) {
+	return e.root.eval(env)
+}
+
+// Match evaluates the expression as a condition, where null is false
+func (e *Expr) Match(env Env) (bool, error) {
+	v, err := e.Eval(env)
+	if err != nil {
+		return false, err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	default:
+		return false, fmt.Errorf("%w: expression must be a bool, got %s", ErrType, kindOf(v))
+	}
+}
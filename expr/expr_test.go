@@ -0,0 +1,141 @@
+package expr
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mapEnv(values map[string]any) Env {
+	return EnvFunc(func(path string) (any, error) {
+		return values[path], nil
+	})
+}
+
+func TestEval(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	env := mapEnv(map[string]any{
+		"start":     start,
+		"end":       start.Add(time.Hour),
+		"items":     []int{1, 2, 3},
+		"maxItems":  int64(5),
+		"name":      " Bob ",
+		"ratio":     float32(0.5),
+		"enabled":   true,
+		"tags":      map[string]string{"a": "b"},
+		"nilSlice":  []string(nil),
+		"pointer":   new(int),
+		"$root.max": uint8(10),
+		"../min":    2,
+	})
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"start < end && len(items) <= maxItems", true},
+		{"sum(items) == 6", true},
+		{"sum(items) / len(items) * 2 - 1 == 3", true},
+		{"7 % 4 == 3 && -ratio < 0", true},
+		{"1 + 2 * 3 == 7", true},
+		{"(1 + 2) * 3 == 9", true},
+		{"trim(lower(name)) + '!' == \"bob!\"", true},
+		{"contains(name, 'o') && startsWith(trim(name), 'B') && !endsWith(name, 'b')", true},
+		{"upper(name) == ' BOB '", true},
+		{"enabled || unknown", true},
+		{"!enabled && unknown", false},
+		{"len(tags) == 1 && len(nilSlice) == 0", true},
+		{"pointer == 0", true},
+		{"$root.max > ../min", true},
+		{"start < now()", true},
+		{"missing == null && !missing", true},
+		{"missing > 1 || missing < 1", false},
+		{"missing + 1 == null", true},
+		{"'a' < 'b' && 'a' != 'b'", true},
+		{"items == null", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Parse(tt.expr)
+			assert.NoError(t, err)
+
+			got, err := e.Match(env)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  error
+		pos  int
+	}{
+		{"", ErrSyntax, 1},
+		{"a <", ErrSyntax, 4},
+		{"a < b < c", ErrSyntax, 7},
+		{"(a", ErrSyntax, 3},
+		{"a # b", ErrSyntax, 3},
+		{"'open", ErrSyntax, 1},
+		{"a.", ErrSyntax, 1},
+		{"exec(a)", ErrSyntax, 1},
+		{"len(a, b) > 1", ErrType, 1},
+		{"len(1) > 1", ErrType, 1},
+		{"'a' - 1 == 0", ErrType, 5},
+		{"!'a'", ErrType, 1},
+		{"1 == 'a'", ErrType, 3},
+		{"now() < 1", ErrType, 7},
+		{"a + 1", ErrType, 1},
+		{"lower(a)", ErrType, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			assert.ErrorIs(t, err, tt.err)
+
+			var exprErr Error
+			if assert.True(t, errors.As(err, &exprErr)) {
+				assert.Equal(t, tt.expr, exprErr.Source)
+				assert.Equal(t, tt.pos, exprErr.Pos+1)
+			}
+		})
+	}
+}
+
+func TestEval_Errors(t *testing.T) {
+	env := mapEnv(map[string]any{"name": "bob", "count": 0})
+
+	for _, source := range []string{"name > 1", "-name == 1", "count && true", "1 / count > 0", "len(count) > 0"} {
+		e := MustParse(source)
+		_, err := e.Match(env)
+		assert.Error(t, err, source)
+	}
+
+	_, err := MustParse("name").Match(env)
+	assert.ErrorIs(t, err, ErrType)
+
+	// Elements of lists are only known at evaluation time
+	for _, items := range []any{[]string{"a"}, []any{1, map[string]any{"a": 1}}} {
+		_, err = MustParse("sum(items) == 0").Match(mapEnv(map[string]any{"items": items}))
+		assert.ErrorIs(t, err, ErrType)
+	}
+}
+
+func TestExpr_Text(t *testing.T) {
+	e := MustParse("a.b > $root.c && len(../items) > 0")
+	assert.Equal(t, []string{"a.b", "$root.c", "../items"}, e.Paths())
+
+	text, err := e.MarshalText()
+	assert.NoError(t, err)
+
+	var decoded Expr
+	assert.NoError(t, decoded.UnmarshalText(text))
+	assert.Equal(t, e.String(), decoded.String())
+
+	assert.Error(t, decoded.UnmarshalText([]byte("a <")))
+}
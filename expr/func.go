@@ -0,0 +1,91 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// function is a built-in function, called with arguments of the types of params or nil
+type function struct {
+	params [][]kind
+	result kind
+	call   func(args []any) (any, error)
+}
+
+var functions = map[string]function{
+	// len returns the number of characters of a string, or of elements of a list or object
+	"len": {
+		params: [][]kind{{kindString, kindList, kindObject}},
+		result: kindNumber,
+		call: func(args []any) (any, error) {
+			switch v := args[0].(type) {
+			case string:
+				return float64(utf8.RuneCountInString(v)), nil
+			case []any:
+				return float64(len(v)), nil
+			case object:
+				return float64(v.len), nil
+			default:
+				return 0.0, nil
+			}
+		},
+	},
+	// sum returns the sum of a list of numbers, where null elements count as 0
+	"sum": {
+		params: [][]kind{{kindList}},
+		result: kindNumber,
+		call: func(args []any) (any, error) {
+			list, _ := args[0].([]any)
+			total := 0.0
+			for _, v := range list {
+				switch v := v.(type) {
+				case float64:
+					total += v
+				case nil:
+				default:
+					return nil, fmt.Errorf("%w: sum can't take a list holding a %s", ErrType, kindOf(v))
+				}
+			}
+			return total, nil
+		},
+	},
+	"now": {
+		result: kindTime,
+		call: func([]any) (any, error) {
+			return time.Now(), nil
+		},
+	},
+	"lower":      stringFunction(strings.ToLower),
+	"upper":      stringFunction(strings.ToUpper),
+	"trim":       stringFunction(strings.TrimSpace),
+	"contains":   predicateFunction(strings.Contains),
+	"startsWith": predicateFunction(strings.HasPrefix),
+	"endsWith":   predicateFunction(strings.HasSuffix),
+}
+
+// stringFunction returns a function of a string, where null is an empty string
+func stringFunction(fn func(string) string) function {
+	return function{
+		params: [][]kind{{kindString}},
+		result: kindString,
+		call: func(args []any) (any, error) {
+			s, _ := args[0].(string)
+			return fn(s), nil
+		},
+	}
+}
+
+// predicateFunction returns a function of two strings, where null is an empty string
+func predicateFunction(fn func(string, string) bool) function {
+	return function{
+		params: [][]kind{{kindString}, {kindString}},
+		result: kindBool,
+		call: func(args []any) (any, error) {
+			s, _ := args[0].(string)
+			sub, _ := args[1].(string)
+			return fn(s, sub), nil
+		},
+	}
+}
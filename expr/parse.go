@@ -0,0 +1,372 @@
+package expr
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenName // paths, keywords and function names
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string // the value of string literals, the source text otherwise
+	pos  int
+}
+
+// operators ordered so that longer operators are matched first
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "%"}
+
+type lexer struct {
+	src string
+	off int
+}
+
+func (l *lexer) next() (token, error) {
+	for l.off < len(l.src) && strings.ContainsRune(" \t\r\n", rune(l.src[l.off])) {
+		l.off++
+	}
+
+	start := l.off
+	if l.off >= len(l.src) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+
+	ch := l.src[l.off]
+	switch {
+	case ch == '(':
+		l.off++
+		return token{kind: tokenLParen, text: "(", pos: start}, nil
+	case ch == ')':
+		l.off++
+		return token{kind: tokenRParen, text: ")", pos: start}, nil
+	case ch == ',':
+		l.off++
+		return token{kind: tokenComma, text: ",", pos: start}, nil
+	case ch == '"' || ch == '\'':
+		return l.string(ch)
+	case isDigit(ch):
+		for l.off < len(l.src) && (isDigit(l.src[l.off]) || l.src[l.off] == '.') {
+			l.off++
+		}
+		return token{kind: tokenNumber, text: l.src[start:l.off], pos: start}, nil
+	case strings.HasPrefix(l.src[l.off:], "../"):
+		for strings.HasPrefix(l.src[l.off:], "../") {
+			l.off += 3
+		}
+		return l.name(start)
+	case isNameStart(ch):
+		return l.name(start)
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.off:], op) {
+			l.off += len(op)
+			return token{kind: tokenOp, text: op, pos: start}, nil
+		}
+	}
+
+	return token{}, errorAt(start, ErrSyntax, "unexpected character %q", ch)
+}
+
+// name scans a path such as items, address.city, $root.limits.max or tags[0]
+func (l *lexer) name(start int) (token, error) {
+	for l.off < len(l.src) {
+		ch := l.src[l.off]
+		switch {
+		case isNameStart(ch) || isDigit(ch) || ch == '.':
+			l.off++
+		case ch == '[':
+			end := strings.IndexByte(l.src[l.off:], ']')
+			if end < 0 {
+				return token{}, errorAt(l.off, ErrSyntax, "unclosed [")
+			}
+			l.off += end + 1
+		default:
+			return l.nameToken(start)
+		}
+	}
+
+	return l.nameToken(start)
+}
+
+func (l *lexer) nameToken(start int) (token, error) {
+	text := l.src[start:l.off]
+	rest := text
+	for strings.HasPrefix(rest, "../") {
+		rest = rest[3:]
+	}
+
+	if rest == "" || rest[0] == '.' || strings.HasSuffix(rest, ".") || strings.Contains(rest, "..") {
+		return token{}, errorAt(start, ErrSyntax, "invalid path %q", text)
+	}
+
+	return token{kind: tokenName, text: text, pos: start}, nil
+}
+
+func (l *lexer) string(quote byte) (token, error) {
+	start := l.off
+	var sb strings.Builder
+	for l.off++; l.off < len(l.src); l.off++ {
+		ch := l.src[l.off]
+		switch ch {
+		case quote:
+			l.off++
+			return token{kind: tokenString, text: sb.String(), pos: start}, nil
+		case '\\':
+			l.off++
+			if l.off >= len(l.src) {
+				break
+			}
+			switch esc := l.src[l.off]; esc {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(esc)
+			}
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+
+	return token{}, errorAt(start, ErrSyntax, "unterminated string")
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || ch == '$' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+// parser is a recursive descent parser, from the lowest precedence:
+//
+//	or:      and ("||" and)*
+//	and:     compare ("&&" compare)*
+//	compare: sum (("==" | "!=" | "<" | "<=" | ">" | ">=") sum)?
+//	sum:     product (("+" | "-") product)*
+//	product: unary (("*" | "/" | "%") unary)*
+//	unary:   ("!" | "-") unary | primary
+//	primary: number | string | true | false | null | name "(" args ")" | path | "(" or ")"
+type parser struct {
+	lexer lexer
+	tok   token
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) parse() (node, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokenEOF {
+		return nil, errorAt(0, ErrSyntax, "empty expression")
+	}
+
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokenEOF {
+		return nil, errorAt(p.tok.pos, ErrSyntax, "unexpected %q", p.tok.text)
+	}
+
+	return n, nil
+}
+
+// binaryLevel parses a left-associative level of binary operators
+func (p *parser) binaryLevel(ops []string, operand func() (node, error)) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind == tokenOp && slices.Contains(ops, p.tok.text) {
+		op := p.tok
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binary{op: op.text, pos: op.pos, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) or() (node, error) {
+	return p.binaryLevel([]string{"||"}, p.and)
+}
+
+func (p *parser) and() (node, error) {
+	return p.binaryLevel([]string{"&&"}, p.compare)
+}
+
+func (p *parser) compare() (node, error) {
+	left, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+
+	ops := []string{"==", "!=", "<", "<=", ">", ">="}
+	if p.tok.kind != tokenOp || !slices.Contains(ops, p.tok.text) {
+		return left, nil
+	}
+
+	op := p.tok
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	right, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokenOp && slices.Contains(ops, p.tok.text) {
+		return nil, errorAt(p.tok.pos, ErrSyntax, "comparisons can't be chained, use &&")
+	}
+
+	return &binary{op: op.text, pos: op.pos, left: left, right: right}, nil
+}
+
+func (p *parser) sum() (node, error) {
+	return p.binaryLevel([]string{"+", "-"}, p.product)
+}
+
+func (p *parser) product() (node, error) {
+	return p.binaryLevel([]string{"*", "/", "%"}, p.unary)
+}
+
+func (p *parser) unary() (node, error) {
+	if p.tok.kind == tokenOp && (p.tok.text == "!" || p.tok.text == "-") {
+		op := p.tok
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &unary{op: op.text, pos: op.pos, operand: operand}, nil
+	}
+
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokenNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, errorAt(tok.pos, ErrSyntax, "invalid number %q", tok.text)
+		}
+		return p.literal(f)
+	case tokenString:
+		return p.literal(tok.text)
+	case tokenLParen:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.tok.kind != tokenRParen {
+			return nil, errorAt(p.tok.pos, ErrSyntax, "expected )")
+		}
+
+		return n, p.advance()
+	case tokenName:
+		switch tok.text {
+		case "true":
+			return p.literal(true)
+		case "false":
+			return p.literal(false)
+		case "null":
+			return p.literal(nil)
+		}
+
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		if p.tok.kind == tokenLParen {
+			return p.call(tok)
+		}
+
+		return &ref{path: tok.text, pos: tok.pos}, nil
+	case tokenEOF:
+		return nil, errorAt(tok.pos, ErrSyntax, "unexpected end of expression")
+	default:
+		return nil, errorAt(tok.pos, ErrSyntax, "unexpected %q", tok.text)
+	}
+}
+
+func (p *parser) literal(v any) (node, error) {
+	return &literal{value: v}, p.advance()
+}
+
+func (p *parser) call(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, errorAt(name.pos, ErrSyntax, "unknown function %s", name.text)
+	}
+
+	c := &call{name: name.text, pos: name.pos, fn: fn}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind != tokenRParen {
+		if len(c.args) > 0 {
+			if p.tok.kind != tokenComma {
+				return nil, errorAt(p.tok.pos, ErrSyntax, "expected , or )")
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+
+		arg, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		c.args = append(c.args, arg)
+	}
+
+	return c, p.advance()
+}
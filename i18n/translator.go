@@ -74,7 +74,12 @@ func (t *Translator) Localize(lang, messageID string, templateData map[string]an
 
 // TranslateError converts a ValidationError to a localized string
 // Positional params are automatically mapped to Arg1, Arg2, Arg3, etc.
+// Internal errors aren't about the input, so they keep their untranslated message
 func (t *Translator) TranslateError(lang string, err schema.ValidationError) string {
+	if err.Category == schema.CategoryInternal {
+		return err.Error()
+	}
+
 	templateData := make(map[string]any)
 	templateData["Path"] = err.Path
 
@@ -0,0 +1,398 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/weilence/schema-validator/schema"
+)
+
+// Draft is the JSON Schema dialect produced by the exporter
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// ExtensionPrefix prefixes the keywords of rules without a JSON Schema equivalent
+const ExtensionPrefix = "x-"
+
+// Kind is the kind of schema a validator is attached to
+type Kind int
+
+const (
+	KindField Kind = iota
+	KindArray
+	KindMap
+	KindObject
+)
+
+// RuleMapper writes the JSON Schema keywords of a validator into node
+type RuleMapper func(node map[string]any, kind Kind, v schema.Validator)
+
+// Exporter converts schema.Schema trees into JSON Schema documents
+type Exporter struct {
+	mappers map[string]RuleMapper
+}
+
+// NewExporter creates an exporter with mappings for the built-in rules
+func NewExporter() *Exporter {
+	e := &Exporter{
+		mappers: make(map[string]RuleMapper),
+	}
+	registerDefault(e)
+
+	return e
+}
+
+// Register sets the mapping of a rule, replacing any existing one
+// Use it to describe custom rules registered in a rule.Registry
+func (e *Exporter) Register(name string, mapper RuleMapper) {
+	e.mappers[name] = mapper
+}
+
+// Export converts s into a JSON Schema document
+// Rules without a mapping are exported as ExtensionPrefix + rule name keywords, while
+// conditional when rules of fields are left out, as their conditions read sibling fields
+func (e *Exporter) Export(s schema.Schema) (map[string]any, error) {
+	ex := &export{
+		exporter: e,
+		root:     s,
+		defNames: make(map[schema.Schema]string),
+		defs:     make(map[string]any),
+	}
+
+	doc, err := ex.standalone(s)
+	if err != nil {
+		return nil, err
+	}
+
+	doc["$schema"] = Draft
+	if len(ex.defs) > 0 {
+		doc["$defs"] = ex.defs
+	}
+
+	return doc, nil
+}
+
+// ExportJSON converts s into an encoded JSON Schema document
+func (e *Exporter) ExportJSON(s schema.Schema) ([]byte, error) {
+	doc, err := e.Export(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(doc)
+}
+
+var defaultExporter = NewExporter()
+
+// Export converts s into a JSON Schema document using the built-in rule mappings
+func Export(s schema.Schema) (map[string]any, error) {
+	return defaultExporter.Export(s)
+}
+
+// export holds the state of a single Export call
+type export struct {
+	exporter *Exporter
+	root     schema.Schema
+	defNames map[schema.Schema]string
+	defs     map[string]any
+}
+
+func (ex *export) node(s schema.Schema) (map[string]any, error) {
+	node := make(map[string]any)
+
+	switch s := s.(type) {
+	case *schema.FieldSchema:
+		ex.validators(node, KindField, s.Validators())
+	case *schema.ArraySchema:
+		node["type"] = "array"
+		ex.validators(node, KindArray, s.Validators())
+
+		items, err := ex.standalone(s.Element())
+		if err != nil {
+			return nil, err
+		}
+		if len(items) > 0 {
+			node["items"] = items
+		}
+	case *schema.MapSchema:
+		node["type"] = "object"
+		ex.validators(node, KindMap, s.Validators())
+
+		if s.Key() != nil {
+			keys, err := ex.standalone(s.Key())
+			if err != nil {
+				return nil, err
+			}
+			if len(keys) > 0 {
+				node["propertyNames"] = keys
+			}
+		}
+
+		if s.Value() != nil {
+			values, err := ex.standalone(s.Value())
+			if err != nil {
+				return nil, err
+			}
+			if len(values) > 0 {
+				node["additionalProperties"] = values
+			}
+		}
+	case *schema.ObjectSchema:
+		node["type"] = "object"
+		ex.validators(node, KindObject, s.Validators())
+
+		properties := make(map[string]any)
+		var required []string
+		for _, name := range s.Fields() {
+			fieldSchema := s.Field(name)
+			property, err := ex.node(fieldSchema)
+			if err != nil {
+				return nil, err
+			}
+
+			if _, ok := property[requiredMarker]; ok {
+				delete(property, requiredMarker)
+				required = append(required, name)
+			}
+
+			properties[name] = property
+		}
+
+		if len(properties) > 0 {
+			node["properties"] = properties
+		}
+		if len(required) > 0 {
+			node["required"] = required
+		}
+
+		for _, c := range s.Conditionals() {
+			if err := ex.conditional(node, c); err != nil {
+				return nil, err
+			}
+		}
+	case *schema.UnionSchema:
+		ex.validators(node, KindField, s.Validators())
+
+		if err := ex.union(node, s); err != nil {
+			return nil, err
+		}
+	case *schema.RefSchema:
+		ref, err := ex.ref(s.Target())
+		if err != nil {
+			return nil, err
+		}
+
+		node["$ref"] = ref
+		ex.validators(node, KindObject, s.Validators())
+	case *schema.CompositeSchema:
+		ex.validators(node, KindField, s.Validators())
+
+		branches := make([]any, 0, len(s.Branches()))
+		for _, branch := range s.Branches() {
+			b, err := ex.standalone(branch)
+			if err != nil {
+				return nil, err
+			}
+			branches = append(branches, b)
+		}
+
+		if s.Mode() == schema.Not {
+			if len(branches) != 1 {
+				return nil, fmt.Errorf("not schema must have exactly one branch, got %d", len(branches))
+			}
+			node["not"] = branches[0]
+		} else {
+			node[s.Mode().String()] = branches
+		}
+	default:
+		return nil, fmt.Errorf("unsupported schema type %T", s)
+	}
+
+	return node, nil
+}
+
+// union exports the variants of a union, as an if/then pair per discriminator value
+// Type unions have no JSON representation of their key and become an anyOf of their variants
+func (ex *export) union(node map[string]any, s *schema.UnionSchema) error {
+	var branches []any
+	values := make([]any, 0, len(s.Variants()))
+	for _, key := range s.Variants() {
+		variant, err := ex.standalone(s.Variant(key))
+		if err != nil {
+			return err
+		}
+
+		if s.Discriminator() == "" {
+			branches = append(branches, variant)
+			continue
+		}
+
+		values = append(values, key)
+		branches = append(branches, map[string]any{
+			"if":   discriminatorNode(s.Discriminator(), map[string]any{"const": key}),
+			"then": variant,
+		})
+	}
+
+	if s.Discriminator() == "" {
+		if s.Fallback() != nil {
+			fallback, err := ex.standalone(s.Fallback())
+			if err != nil {
+				return err
+			}
+			branches = append(branches, fallback)
+		}
+
+		node["anyOf"] = branches
+		return nil
+	}
+
+	known := discriminatorNode(s.Discriminator(), map[string]any{"enum": values})
+	if s.Fallback() != nil {
+		fallback, err := ex.standalone(s.Fallback())
+		if err != nil {
+			return err
+		}
+		branches = append(branches, map[string]any{"if": map[string]any{"not": known}, "then": fallback})
+	} else {
+		branches = append(branches, known)
+	}
+
+	allOf, _ := node["allOf"].([]any)
+	node["allOf"] = append(allOf, branches...)
+	return nil
+}
+
+// conditional exports a conditional as an if/then/else entry of allOf
+func (ex *export) conditional(node map[string]any, c *schema.Conditional) error {
+	cond, err := conditionNode(c.Condition())
+	if err != nil {
+		return err
+	}
+
+	entry := map[string]any{"if": cond}
+	for keyword, s := range map[string]schema.Schema{"then": c.ThenSchema(), "else": c.ElseSchema()} {
+		if s == nil {
+			continue
+		}
+
+		branch, err := ex.standalone(s)
+		if err != nil {
+			return err
+		}
+		entry[keyword] = branch
+	}
+
+	allOf, _ := node["allOf"].([]any)
+	node["allOf"] = append(allOf, entry)
+	return nil
+}
+
+// conditionNode returns the schema matching the same objects as a condition
+// Only the built-in conditions on paths within the object can be expressed
+func conditionNode(c schema.Condition) (map[string]any, error) {
+	var path string
+	var value map[string]any
+	switch c := c.(type) {
+	case schema.EqCondition:
+		path, value = c.Path, map[string]any{"const": c.Value}
+	case schema.InCondition:
+		path, value = c.Path, map[string]any{"enum": c.Values}
+	case schema.PresentCondition:
+		path, value = c.Path, map[string]any{"not": map[string]any{"type": "null"}}
+	default:
+		return nil, fmt.Errorf("unsupported condition %T", c)
+	}
+
+	if strings.HasPrefix(path, schema.RootPathPrefix) || strings.HasPrefix(path, schema.ParentPathPrefix) {
+		return nil, fmt.Errorf("unsupported condition path %s outside of the object", path)
+	}
+
+	return discriminatorNode(path, value), nil
+}
+
+// discriminatorNode returns a schema requiring the value at a dotted path to match value
+func discriminatorNode(path string, value map[string]any) map[string]any {
+	segments := strings.Split(path, ".")
+	node := value
+	for i := len(segments) - 1; i >= 0; i-- {
+		node = map[string]any{
+			"properties": map[string]any{segments[i]: node},
+			"required":   []string{segments[i]},
+		}
+	}
+
+	return node
+}
+
+// standalone exports a schema that is not an object property, where required
+// can't be expressed through the parent and becomes an extension keyword
+func (ex *export) standalone(s schema.Schema) (map[string]any, error) {
+	node, err := ex.node(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return standaloneRequired(node), nil
+}
+
+// ref returns the reference to target, adding it to $defs unless it is the root
+func (ex *export) ref(target schema.Schema) (string, error) {
+	if target == nil {
+		return "", fmt.Errorf("unresolved schema reference")
+	}
+
+	if target == ex.root {
+		return "#", nil
+	}
+
+	if name, ok := ex.defNames[target]; ok {
+		return "#/$defs/" + name, nil
+	}
+
+	name := fmt.Sprintf("ref%d", len(ex.defNames)+1)
+	ex.defNames[target] = name
+
+	def, err := ex.standalone(target)
+	if err != nil {
+		return "", err
+	}
+	ex.defs[name] = def
+
+	return "#/$defs/" + name, nil
+}
+
+func (ex *export) validators(node map[string]any, kind Kind, validators []schema.Validator) {
+	ex.exporter.validators(node, kind, validators)
+}
+
+// validators writes the keywords of validators into node
+// Validators limited to groups other than the default group don't apply to the exported schema
+func (e *Exporter) validators(node map[string]any, kind Kind, validators []schema.Validator) {
+	for _, v := range validators {
+		if g, ok := v.(schema.GroupedValidator); ok && !slices.Contains(g.Groups(), schema.DefaultGroup) {
+			continue
+		}
+
+		if mapper, ok := e.mappers[v.Name()]; ok {
+			mapper(node, kind, v)
+			continue
+		}
+
+		node[ExtensionPrefix+v.Name()] = extensionValue(v.Params())
+	}
+}
+
+// extensionValue is the value of an extension keyword: true without params,
+// the param itself for a single param and the list of params otherwise
+func extensionValue(params []any) any {
+	switch len(params) {
+	case 0:
+		return true
+	case 1:
+		return params[0]
+	default:
+		return params
+	}
+}
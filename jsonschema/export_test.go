@@ -0,0 +1,225 @@
+package jsonschema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	validator "github.com/weilence/schema-validator"
+	"github.com/weilence/schema-validator/rule"
+	"github.com/weilence/schema-validator/schema"
+)
+
+type exportAddress struct {
+	City    string `json:"city" validate:"required|max=50"`
+	Country string `json:"country" validate:"iso3166_1_alpha2"`
+}
+
+type exportUser struct {
+	Email    string            `json:"email" validate:"required|email"`
+	Name     string            `json:"name" validate:"min=2|max=20"`
+	Age      int               `json:"age" validate:"gte=18|lt=130"`
+	Role     string            `json:"role" validate:"oneof=admin,user"`
+	Nickname string            `json:"nickname" validate:"omitempty|startswith=@|endswith=!"`
+	Tags     []string          `json:"tags" validate:"max=5|dive|required|len=3"`
+	Labels   map[string]string `json:"labels" validate:"dive|keys|alpha|endkeys|max=10"`
+	Address  *exportAddress    `json:"address" validate:"required"`
+	Confirm  string            `json:"confirm" validate:"eqfield=Email"`
+	Friends  []*exportUser     `json:"friends"`
+}
+
+func TestExport(t *testing.T) {
+	s, err := validator.Parse(reflect.TypeFor[exportUser]())
+	assert.NoError(t, err)
+
+	doc, err := NewExporter().ExportJSON(s)
+	assert.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"required": ["email", "address"],
+		"properties": {
+			"email": {"format": "email"},
+			"name": {"minimum": 2, "minLength": 2, "maximum": 20, "maxLength": 20},
+			"age": {"minimum": 18, "minLength": 18, "exclusiveMaximum": 130, "maxLength": 129},
+			"role": {"enum": ["admin", "user"]},
+			"nickname": {"pattern": "^@", "allOf": [{"pattern": "!$"}]},
+			"tags": {
+				"type": "array",
+				"maxItems": 5,
+				"items": {"x-required": true, "minLength": 3, "maxLength": 3}
+			},
+			"labels": {
+				"type": "object",
+				"propertyNames": {"x-alpha": true},
+				"additionalProperties": {"maximum": 10, "maxLength": 10}
+			},
+			"address": {
+				"type": "object",
+				"required": ["city"],
+				"properties": {
+					"city": {"maximum": 50, "maxLength": 50},
+					"country": {"pattern": "^[A-Z]{2}$"}
+				}
+			},
+			"confirm": {"x-eqfield": "Email"},
+			"friends": {"type": "array", "items": {"$ref": "#"}}
+		}
+	}`, string(doc))
+}
+
+func TestExport_CustomRule(t *testing.T) {
+	r := rule.NewRegistry()
+	rule.RegisterDefault(r)
+	r.Register("slug", func(ctx *schema.Context) error { return nil })
+	r.Register("between", func(ctx *schema.Context, min, max int) error { return nil })
+
+	s := schema.NewObject().
+		AddField("slug", schema.NewField().AddValidator(r.NewValidator("slug"))).
+		AddField("score", schema.NewField().AddValidator(r.NewValidator("between", 1, 5)))
+
+	doc, err := Export(s)
+	assert.NoError(t, err)
+	properties := doc["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"x-slug": true}, properties["slug"])
+	assert.Equal(t, map[string]any{"x-between": []any{1, 5}}, properties["score"])
+
+	e := NewExporter()
+	e.Register("slug", func(node map[string]any, kind Kind, v schema.Validator) {
+		node["pattern"] = "^[a-z0-9-]+$"
+	})
+	e.Register("between", func(node map[string]any, kind Kind, v schema.Validator) {
+		node["minimum"], node["maximum"] = v.Params()[0], v.Params()[1]
+	})
+
+	doc, err = e.Export(s)
+	assert.NoError(t, err)
+	properties = doc["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"pattern": "^[a-z0-9-]+$"}, properties["slug"])
+	assert.Equal(t, map[string]any{"minimum": 1, "maximum": 5}, properties["score"])
+}
+
+func TestExport_Defs(t *testing.T) {
+	node := schema.NewObject()
+	node.AddField("children", schema.NewArray(schema.NewRef().Resolve(node)))
+	root := schema.NewObject().AddField("tree", node)
+
+	doc, err := Export(root)
+	assert.NoError(t, err)
+
+	tree := doc["properties"].(map[string]any)["tree"].(map[string]any)
+	children := tree["properties"].(map[string]any)["children"].(map[string]any)
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/ref1"}, children["items"])
+	assert.Contains(t, doc["$defs"], "ref1")
+}
+
+func TestExport_Combinators(t *testing.T) {
+	type theme struct {
+		Color string `json:"color" validate:"anyOf(hexcolor|allOf(startswith=rgb|contains=b))"`
+		Name  string `json:"name" validate:"not(oneof=admin,root)"`
+	}
+
+	s, err := validator.Parse(reflect.TypeFor[theme]())
+	assert.NoError(t, err)
+
+	doc, err := NewExporter().ExportJSON(s)
+	assert.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"color": {"anyOf": [
+				{"pattern": "^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$"},
+				{"allOf": [{"pattern": "^rgb"}, {"pattern": "b"}]}
+			]},
+			"name": {"not": {"enum": ["admin", "root"]}}
+		}
+	}`, string(doc))
+}
+
+func TestExport_Union(t *testing.T) {
+	click := validator.Object().WithField("x", validator.Field().AddValidator("required").Build()).Build()
+	key := validator.Object().WithField("code", validator.Field().AddValidator("max", 5).Build()).Build()
+	s := validator.Union("type").Variant("click", click).Variant("key", key).Build()
+
+	doc, err := NewExporter().ExportJSON(s)
+	assert.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"allOf": [
+			{
+				"if": {"properties": {"type": {"const": "click"}}, "required": ["type"]},
+				"then": {"type": "object", "properties": {"x": {}}, "required": ["x"]}
+			},
+			{
+				"if": {"properties": {"type": {"const": "key"}}, "required": ["type"]},
+				"then": {"type": "object", "properties": {"code": {"maxLength": 5, "maximum": 5}}}
+			},
+			{"properties": {"type": {"enum": ["click", "key"]}}, "required": ["type"]}
+		]
+	}`, string(doc))
+}
+
+func TestExport_Conditional(t *testing.T) {
+	s := validator.Object().
+		WithField("country", validator.Field().Build()).
+		When(schema.In("country", "US", "CA")).
+		Then(validator.Object().WithField("zipCode", validator.Field().AddValidator("max", 5).Build()).Build()).
+		Else(validator.Object().WithField("zipCode", validator.Field().AddValidator("max", 10).Build()).Build()).
+		Build()
+
+	doc, err := NewExporter().ExportJSON(s)
+	assert.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {"country": {}},
+		"allOf": [{
+			"if": {"properties": {"country": {"enum": ["US", "CA"]}}, "required": ["country"]},
+			"then": {"type": "object", "properties": {"zipCode": {"maxLength": 5, "maximum": 5}}},
+			"else": {"type": "object", "properties": {"zipCode": {"maxLength": 10, "maximum": 10}}}
+		}]
+	}`, string(doc))
+
+	// Conditional rules of fields are left out, whatever their condition
+	custom := schema.ConditionFunc(func(*schema.Context) (bool, error) { return true, nil })
+	s = validator.Object().
+		WithField("zipCode", validator.Field().
+			AddValidator("max", 10).
+			Build().
+			AddValidator(rule.When(custom, []schema.Validator{rule.NewValidator("len", 5)}, nil))).
+		Build()
+	doc, err = NewExporter().ExportJSON(s)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {"zipCode": {"maxLength": 10, "maximum": 10}}
+	}`, string(doc))
+
+	// Conditions on data outside of the object have no JSON Schema equivalent
+	s = validator.Object().When(schema.Eq("$root.mode", "free")).Then(validator.Field().Build()).Build()
+	_, err = NewExporter().ExportJSON(s)
+	assert.Error(t, err)
+}
+
+func TestExport_Groups(t *testing.T) {
+	s := validator.Object().
+		WithField("id", validator.Field().Groups("update").Required().Build()).
+		WithField("name", validator.Field().Groups("default", "update").AddValidator("max", 5).Build()).
+		Build()
+
+	doc, err := NewExporter().ExportJSON(s)
+	assert.NoError(t, err)
+
+	// Only the rules of the default group apply
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {"id": {}, "name": {"maxLength": 5, "maximum": 5}}
+	}`, string(doc))
+}
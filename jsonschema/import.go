@@ -0,0 +1,708 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/weilence/schema-validator/data"
+	"github.com/weilence/schema-validator/rule"
+	"github.com/weilence/schema-validator/schema"
+)
+
+var (
+	// ErrUnsupported is reported for keywords, and keyword forms, the importer can't express
+	ErrUnsupported = errors.New("unsupported keyword")
+	// ErrInvalidKeyword is reported for keywords whose value doesn't match the specification
+	ErrInvalidKeyword = errors.New("invalid keyword value")
+)
+
+// ImportError is a problem with a keyword of an imported document
+type ImportError struct {
+	// Pointer is the JSON pointer of the schema holding the keyword, e.g. "#/properties/name"
+	Pointer string
+	Keyword string
+	Err     error
+}
+
+func (e ImportError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.Pointer, e.Keyword, e.Err)
+}
+
+func (e ImportError) Unwrap() error {
+	return e.Err
+}
+
+// ImportErrors holds every problem found in an imported document
+type ImportErrors []ImportError
+
+func (e ImportErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+func (e ImportErrors) Error() string {
+	return errors.Join(e.Unwrap()...).Error()
+}
+
+// annotations are keywords without effect on validation
+var annotations = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"$defs":       true,
+	"definitions": true,
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+	"deprecated":  true,
+	"readOnly":    true,
+	"writeOnly":   true,
+}
+
+var keywords = map[string]bool{
+	"type":                 true,
+	"enum":                 true,
+	"const":                true,
+	"properties":           true,
+	"required":             true,
+	"additionalProperties": true,
+	"propertyNames":        true,
+	"minProperties":        true,
+	"maxProperties":        true,
+	"items":                true,
+	"minItems":             true,
+	"maxItems":             true,
+	"minimum":              true,
+	"maximum":              true,
+	"exclusiveMinimum":     true,
+	"exclusiveMaximum":     true,
+	"minLength":            true,
+	"maxLength":            true,
+	"pattern":              true,
+	"format":               true,
+	"$ref":                 true,
+	"allOf":                true,
+	"anyOf":                true,
+	"oneOf":                true,
+	"not":                  true,
+}
+
+var (
+	objectKeywords = []string{"properties", "required", "additionalProperties", "propertyNames", "minProperties", "maxProperties"}
+	arrayKeywords  = []string{"items", "minItems", "maxItems"}
+)
+
+// Importer compiles JSON Schema documents into schema.Schema trees built from the rules of a registry
+//
+// Keywords map to rules the same way the exporter maps rules to keywords. Keywords that
+// only apply to some JSON types, like minimum or pattern, skip values of other types.
+// Object and array keywords without a type keyword imply the type. Required properties
+// must be present and missing optional properties skip their other keywords, while null
+// properties are validated like other values, against the type keyword.
+// Properties are validated in sorted order, as JSON objects have no order
+type Importer struct {
+	registry *rule.Registry
+	formats  map[string]string
+}
+
+// NewImporter creates an importer using the rules of registry, nil for the default registry
+func NewImporter(registry *rule.Registry) *Importer {
+	if registry == nil {
+		registry = rule.DefaultRegistry()
+	}
+
+	i := &Importer{
+		registry: registry,
+		formats: map[string]string{
+			"email":     "email",
+			"uri":       "uri",
+			"uuid":      "uuid",
+			"ipv4":      "ipv4",
+			"ipv6":      "ipv6",
+			"hostname":  "hostname",
+			"date-time": "datetime",
+		},
+	}
+
+	return i
+}
+
+// RegisterFormat sets the rule used for a format keyword value, replacing any existing one
+func (i *Importer) RegisterFormat(format, ruleName string) {
+	i.formats[format] = ruleName
+}
+
+// Import compiles a decoded JSON Schema document
+// All problems in the document are reported together as ImportErrors
+func (i *Importer) Import(doc any) (schema.Schema, error) {
+	im := &importer{
+		Importer: i,
+		doc:      doc,
+		compiled: make(map[string]schema.Schema),
+	}
+
+	root := im.compile(doc, "#", nil)
+	im.compiled["#"] = root
+
+	// Targets may hold further references, so the list grows while it is resolved
+	for n := 0; n < len(im.refs); n++ {
+		ref := im.refs[n]
+		ref.schema.Resolve(im.target(ref.pointer, ref.ref))
+	}
+
+	for _, ref := range im.refs {
+		if ref.schema.Cycles() {
+			im.errorf(ref.pointer, "$ref", ErrInvalidKeyword, "circular reference %q", ref.ref)
+		}
+	}
+
+	if len(im.errs) > 0 {
+		return nil, im.errs
+	}
+
+	return root, nil
+}
+
+// ImportJSON compiles an encoded JSON Schema document
+func (i *Importer) ImportJSON(doc []byte) (schema.Schema, error) {
+	var decoded any
+	if err := json.Unmarshal(doc, &decoded); err != nil {
+		return nil, err
+	}
+
+	return i.Import(decoded)
+}
+
+// Import compiles a decoded JSON Schema document using the rules of registry, nil for the default registry
+func Import(doc any, registry *rule.Registry) (schema.Schema, error) {
+	return NewImporter(registry).Import(doc)
+}
+
+// ImportJSON compiles an encoded JSON Schema document using the rules of registry, nil for the default registry
+func ImportJSON(doc []byte, registry *rule.Registry) (schema.Schema, error) {
+	return NewImporter(registry).ImportJSON(doc)
+}
+
+type pendingRef struct {
+	schema  *schema.RefSchema
+	pointer string // pointer of the schema holding $ref
+	ref     string
+}
+
+// importer holds the state of a single Import call
+type importer struct {
+	*Importer
+
+	doc      any
+	compiled map[string]schema.Schema // reference targets by pointer
+	refs     []pendingRef
+	errs     ImportErrors
+}
+
+func (im *importer) errorf(pointer, keyword string, err error, format string, args ...any) {
+	if format != "" {
+		err = fmt.Errorf("%w: %s", err, fmt.Sprintf(format, args...))
+	}
+
+	im.errs = append(im.errs, ImportError{Pointer: pointer, Keyword: keyword, Err: err})
+}
+
+// target returns the compiled schema a $ref points to
+func (im *importer) target(pointer, ref string) schema.Schema {
+	if !strings.HasPrefix(ref, "#") {
+		im.errorf(pointer, "$ref", ErrUnsupported, "only references within the document are supported, got %q", ref)
+		return schema.NewField()
+	}
+
+	target := ref
+	if target == "#/" {
+		target = "#"
+	}
+
+	if s, ok := im.compiled[target]; ok {
+		return s
+	}
+
+	node, err := resolvePointer(im.doc, target)
+	if err != nil {
+		im.errorf(pointer, "$ref", ErrInvalidKeyword, "%v", err)
+		return schema.NewField()
+	}
+
+	s := im.compile(node, target, nil)
+	im.compiled[target] = s
+	return s
+}
+
+// compile compiles the schema node at pointer
+// leading validators run before the validators of the node's own keywords
+func (im *importer) compile(node any, pointer string, leading []schema.Validator) schema.Schema {
+	switch node := node.(type) {
+	case bool:
+		var s schema.Schema = schema.NewField()
+		if !node {
+			s = schema.NewNot(schema.NewField())
+		}
+
+		for _, v := range leading {
+			s.AddValidator(v)
+		}
+		return s
+	case map[string]any:
+		return im.compileObject(node, pointer, leading)
+	default:
+		im.errorf(pointer, "", ErrInvalidKeyword, "schema must be an object or a boolean, got %T", node)
+		return schema.NewField()
+	}
+}
+
+func (im *importer) compileObject(node map[string]any, pointer string, leading []schema.Validator) schema.Schema {
+	for _, keyword := range slices.Sorted(maps.Keys(node)) {
+		if !keywords[keyword] && !annotations[keyword] {
+			im.errorf(pointer, keyword, ErrUnsupported, "")
+		}
+	}
+
+	types := im.types(node, pointer)
+	hasObjectKeywords := slices.ContainsFunc(objectKeywords, func(k string) bool { _, ok := node[k]; return ok })
+	hasArrayKeywords := slices.ContainsFunc(arrayKeywords, func(k string) bool { _, ok := node[k]; return ok })
+	if hasObjectKeywords && hasArrayKeywords {
+		im.errorf(pointer, "", ErrUnsupported, "object and array keywords in the same schema")
+		hasArrayKeywords = false
+	}
+
+	// Structural keywords imply their type, so values of the wrong shape are reported instead of failing validation
+	if types == nil && hasObjectKeywords {
+		types = []string{"object"}
+	}
+	if types == nil && hasArrayKeywords {
+		types = []string{"array"}
+	}
+
+	var s schema.Schema
+	switch {
+	case hasObjectKeywords || slices.Equal(types, []string{"object"}):
+		s = im.compileObjectShape(node, pointer)
+	case hasArrayKeywords || slices.Equal(types, []string{"array"}):
+		s = im.compileArrayShape(node, pointer)
+	default:
+		s = schema.NewField()
+	}
+
+	validators := slices.Clone(leading)
+	own := false
+	add := func(v schema.Validator) {
+		validators = append(validators, v)
+		own = true
+	}
+
+	if types != nil {
+		if v := im.rule(pointer, "type", "type", types); v != nil {
+			add(v)
+		}
+	}
+
+	if enum, ok := node["enum"]; ok {
+		values, isList := enum.([]any)
+		if !isList || len(values) == 0 {
+			im.errorf(pointer, "enum", ErrInvalidKeyword, "must be a non-empty array")
+		} else if v := im.enum(pointer, "enum", values); v != nil {
+			add(v)
+		}
+	}
+
+	if value, ok := node["const"]; ok {
+		if v := im.enum(pointer, "const", []any{value}); v != nil {
+			add(v)
+		}
+	}
+
+	for _, bound := range []struct{ keyword, rule string }{
+		{"minimum", "gte"},
+		{"maximum", "lte"},
+		{"exclusiveMinimum", "gt"},
+		{"exclusiveMaximum", "lt"},
+	} {
+		value, ok := node[bound.keyword]
+		if !ok {
+			continue
+		}
+
+		n, isNumber := value.(float64)
+		if !isNumber {
+			im.errorf(pointer, bound.keyword, ErrInvalidKeyword, "must be a number, got %T", value)
+			continue
+		}
+
+		if v := im.rule(pointer, bound.keyword, bound.rule, strconv.FormatFloat(n, 'f', -1, 64)); v != nil {
+			add(guard(v, "number"))
+		}
+	}
+
+	for _, length := range []struct{ keyword, rule, jsonType string }{
+		{"minLength", "min", "string"},
+		{"maxLength", "max", "string"},
+		{"minItems", "min", "array"},
+		{"maxItems", "max", "array"},
+		{"minProperties", "min", "object"},
+		{"maxProperties", "max", "object"},
+	} {
+		value, ok := node[length.keyword]
+		if !ok {
+			continue
+		}
+
+		n, isNumber := value.(float64)
+		if !isNumber || n < 0 || n != float64(int(n)) {
+			im.errorf(pointer, length.keyword, ErrInvalidKeyword, "must be a non-negative integer, got %v", value)
+			continue
+		}
+
+		if v := im.rule(pointer, length.keyword, length.rule, int(n)); v != nil {
+			add(guard(v, length.jsonType))
+		}
+	}
+
+	if value, ok := node["pattern"]; ok {
+		pattern, isString := value.(string)
+		if !isString {
+			im.errorf(pointer, "pattern", ErrInvalidKeyword, "must be a string, got %T", value)
+		} else if v := im.rule(pointer, "pattern", "pattern", pattern); v != nil {
+			add(guard(v, "string"))
+		}
+	}
+
+	if value, ok := node["format"]; ok {
+		format, _ := value.(string)
+		if ruleName, known := im.formats[format]; !known {
+			im.errorf(pointer, "format", ErrUnsupported, "unknown format %v", value)
+		} else if v := im.rule(pointer, "format", ruleName); v != nil {
+			add(guard(v, "string"))
+		}
+	}
+
+	own = own || hasObjectKeywords || hasArrayKeywords
+
+	// $ref and the combinators are combined with the node's own keywords through allOf
+	var parts []schema.Schema
+	if value, ok := node["$ref"]; ok {
+		ref, isString := value.(string)
+		if !isString {
+			im.errorf(pointer, "$ref", ErrInvalidKeyword, "must be a string, got %T", value)
+		} else {
+			refSchema := schema.NewRef()
+			im.refs = append(im.refs, pendingRef{schema: refSchema, pointer: pointer, ref: ref})
+			parts = append(parts, refSchema)
+		}
+	}
+
+	for _, mode := range []schema.CompositeMode{schema.AllOf, schema.AnyOf, schema.OneOf} {
+		value, ok := node[mode.String()]
+		if !ok {
+			continue
+		}
+
+		list, isList := value.([]any)
+		if !isList || len(list) == 0 {
+			im.errorf(pointer, mode.String(), ErrInvalidKeyword, "must be a non-empty array")
+			continue
+		}
+
+		branches := make([]schema.Schema, len(list))
+		for n, branch := range list {
+			branches[n] = im.compile(branch, fmt.Sprintf("%s/%s/%d", pointer, mode, n), nil)
+		}
+		parts = append(parts, schema.NewComposite(mode, branches...))
+	}
+
+	if value, ok := node["not"]; ok {
+		parts = append(parts, schema.NewNot(im.compile(value, pointer+"/not", nil)))
+	}
+
+	if len(parts) == 0 {
+		for _, v := range validators {
+			s.AddValidator(v)
+		}
+
+		return s
+	}
+
+	if own {
+		for _, v := range validators[len(leading):] {
+			s.AddValidator(v)
+		}
+		parts = append([]schema.Schema{s}, parts...)
+	}
+
+	combined := parts[0]
+	if len(parts) > 1 {
+		combined = schema.NewAllOf(parts...)
+	}
+
+	for _, v := range leading {
+		combined.AddValidator(v)
+	}
+
+	return combined
+}
+
+// compileObjectShape compiles the object keywords into an object schema for fixed
+// properties, or a map schema for additionalProperties and propertyNames
+func (im *importer) compileObjectShape(node map[string]any, pointer string) schema.Schema {
+	properties, hasProperties := node["properties"]
+	required, hasRequired := node["required"]
+	additional, hasAdditional := node["additionalProperties"]
+	propertyNames, hasPropertyNames := node["propertyNames"]
+
+	if !hasProperties && !hasRequired && (hasAdditional || hasPropertyNames) {
+		var key, value schema.Schema
+		if hasPropertyNames {
+			key = im.compile(propertyNames, pointer+"/propertyNames", nil)
+		}
+		if hasAdditional && additional != true {
+			value = im.compile(additional, pointer+"/additionalProperties", nil)
+		}
+
+		return schema.NewMap(key, value)
+	}
+
+	if hasAdditional && additional != true && additional != false {
+		im.errorf(pointer, "additionalProperties", ErrUnsupported, "only a boolean is supported together with properties")
+	}
+	if hasPropertyNames {
+		im.errorf(pointer, "propertyNames", ErrUnsupported, "not supported together with properties")
+	}
+
+	var requiredNames []string
+	if hasRequired {
+		list, isList := required.([]any)
+		for _, name := range list {
+			if name, isString := name.(string); isString {
+				requiredNames = append(requiredNames, name)
+			} else {
+				isList = false
+			}
+		}
+
+		if !isList {
+			im.errorf(pointer, "required", ErrInvalidKeyword, "must be an array of strings")
+		}
+	}
+
+	props, isMap := properties.(map[string]any)
+	if hasProperties && !isMap {
+		im.errorf(pointer, "properties", ErrInvalidKeyword, "must be an object, got %T", properties)
+	}
+
+	obj := schema.NewObject()
+	for _, name := range slices.Sorted(maps.Keys(props)) {
+		obj.AddField(name, im.compile(props[name], pointer+"/properties/"+escapePointer(name), im.presence(pointer, slices.Contains(requiredNames, name))))
+	}
+
+	// Required properties without a schema only have to be present
+	for _, name := range requiredNames {
+		if _, ok := props[name]; !ok && obj.Field(name) == nil {
+			obj.AddField(name, im.compile(true, pointer+"/properties/"+escapePointer(name), im.presence(pointer, true)))
+		}
+	}
+
+	if additional == false {
+		if v := im.rule(pointer, "additionalProperties", "allowed_keys", obj.Fields()); v != nil {
+			obj.AddValidator(v)
+		}
+	}
+
+	return obj
+}
+
+// presence returns the validators that run first on a property
+// A missing required property is reported once, without the errors of its other keywords
+func (im *importer) presence(pointer string, required bool) []schema.Validator {
+	var validators []schema.Validator
+	if required {
+		if v := im.rule(pointer, "required", "exists"); v != nil {
+			validators = append(validators, v)
+		}
+	}
+
+	if v := im.rule(pointer, "required", "omitabsent"); v != nil {
+		validators = append(validators, v)
+	}
+
+	return validators
+}
+
+func (im *importer) compileArrayShape(node map[string]any, pointer string) schema.Schema {
+	items, ok := node["items"]
+	if !ok {
+		return schema.NewArray(schema.NewField())
+	}
+
+	if _, isList := items.([]any); isList {
+		im.errorf(pointer, "items", ErrUnsupported, "tuple validation is not supported")
+		return schema.NewArray(schema.NewField())
+	}
+
+	return schema.NewArray(im.compile(items, pointer+"/items", nil))
+}
+
+// types returns the values of the type keyword, nil if it is absent
+func (im *importer) types(node map[string]any, pointer string) []string {
+	value, ok := node["type"]
+	if !ok {
+		return nil
+	}
+
+	var types []string
+	switch value := value.(type) {
+	case string:
+		types = []string{value}
+	case []any:
+		for _, t := range value {
+			if t, isString := t.(string); isString {
+				types = append(types, t)
+			}
+		}
+
+		if len(types) != len(value) {
+			types = nil
+		}
+	}
+
+	if len(types) == 0 {
+		im.errorf(pointer, "type", ErrInvalidKeyword, "must be a string or an array of strings")
+		return nil
+	}
+
+	for _, t := range types {
+		switch t {
+		case "string", "number", "integer", "boolean", "array", "object", "null":
+		default:
+			im.errorf(pointer, "type", ErrInvalidKeyword, "unknown type %q", t)
+			return nil
+		}
+	}
+
+	return types
+}
+
+// enum returns a oneof rule for a list of scalar values
+func (im *importer) enum(pointer, keyword string, values []any) schema.Validator {
+	options := make([]string, len(values))
+	for n, value := range values {
+		switch value := value.(type) {
+		case string:
+			options[n] = value
+		case float64:
+			options[n] = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			options[n] = strconv.FormatBool(value)
+		default:
+			im.errorf(pointer, keyword, ErrUnsupported, "only string, number and boolean values are supported, got %v", value)
+			return nil
+		}
+	}
+
+	return im.rule(pointer, keyword, "oneof", options)
+}
+
+// rule creates a validator from the registry, reporting missing rules against keyword
+func (im *importer) rule(pointer, keyword, name string, params ...any) schema.Validator {
+	v, err := im.registry.NewValidatorE(name, params...)
+	if err != nil {
+		im.errorf(pointer, keyword, err, "")
+		return nil
+	}
+
+	return v
+}
+
+// guarded runs a keyword's rule only for values of the JSON type the keyword applies to
+type guarded struct {
+	schema.Validator
+	jsonType string
+}
+
+func guard(v schema.Validator, jsonType string) schema.Validator {
+	return guarded{Validator: v, jsonType: jsonType}
+}
+
+func (g guarded) Validate(ctx *schema.Context) error {
+	if !isType(ctx.Value(), g.jsonType) {
+		return nil
+	}
+
+	return g.Validator.Validate(ctx)
+}
+
+func isType(v *data.Value, jsonType string) bool {
+	rv := reflect.ValueOf(v.Any())
+	switch jsonType {
+	case "string":
+		return rv.Kind() == reflect.String
+	case "number":
+		return rv.CanInt() || rv.CanUint() || rv.CanFloat()
+	case "array":
+		return rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array
+	case "object":
+		return rv.Kind() == reflect.Map || rv.Kind() == reflect.Struct
+	default:
+		return false
+	}
+}
+
+// resolvePointer returns the value at a "#/a/b" JSON pointer within doc
+func resolvePointer(doc any, pointer string) (any, error) {
+	fragment, err := url.PathUnescape(strings.TrimPrefix(pointer, "#"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid reference %q: %w", pointer, err)
+	}
+
+	if fragment == "" {
+		return doc, nil
+	}
+
+	if !strings.HasPrefix(fragment, "/") {
+		return nil, fmt.Errorf("%w: anchor reference %q", ErrUnsupported, pointer)
+	}
+
+	node := doc
+	for _, token := range strings.Split(fragment[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+
+		switch n := node.(type) {
+		case map[string]any:
+			value, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("reference %q not found", pointer)
+			}
+			node = value
+		case []any:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(n) {
+				return nil, fmt.Errorf("reference %q not found", pointer)
+			}
+			node = n[index]
+		default:
+			return nil, fmt.Errorf("reference %q not found", pointer)
+		}
+	}
+
+	return node, nil
+}
+
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
@@ -0,0 +1,330 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	validator "github.com/weilence/schema-validator"
+	"github.com/weilence/schema-validator/rule"
+	"github.com/weilence/schema-validator/schema"
+)
+
+const importDoc = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "order",
+	"type": "object",
+	"required": ["id", "email", "items"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "string", "format": "uuid"},
+		"email": {"type": "string", "format": "email"},
+		"status": {"enum": ["new", "paid"]},
+		"note": {"type": ["string", "null"], "maxLength": 5},
+		"items": {
+			"type": "array",
+			"minItems": 1,
+			"items": {"$ref": "#/$defs/item"}
+		},
+		"labels": {
+			"propertyNames": {"pattern": "^[a-z]+$"},
+			"additionalProperties": {"type": "string"}
+		},
+		"parent": {"$ref": "#"}
+	},
+	"$defs": {
+		"item": {
+			"type": "object",
+			"required": ["sku", "qty"],
+			"properties": {
+				"sku": {"type": "string", "pattern": "^[A-Z]{3}-\\d+$"},
+				"qty": {"type": "integer", "minimum": 1, "exclusiveMaximum": 100}
+			}
+		}
+	}
+}`
+
+func decode(t *testing.T, payload string) any {
+	t.Helper()
+
+	var v any
+	if err := json.Unmarshal([]byte(payload), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// failures returns the "path: code" of each validation error
+func failures(t *testing.T, err error) []string {
+	t.Helper()
+
+	if err == nil {
+		return nil
+	}
+
+	var errs schema.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+
+	res := make([]string, len(errs))
+	for i, e := range errs {
+		res[i] = e.Path + ": " + e.Code
+	}
+	return res
+}
+
+func TestImport(t *testing.T) {
+	s, err := ImportJSON([]byte(importDoc), nil)
+	assert.NoError(t, err)
+
+	v := validator.NewFromSchema(s)
+
+	// Properties are validated in sorted order, as JSON objects have no order
+	tests := []struct {
+		name    string
+		payload string
+		want    []string
+	}{
+		{
+			name:    "valid",
+			payload: `{"id": "3f1e8a52-6c1d-4c3a-9f55-0f2a8b1c2d3e", "email": "a@b.co", "status": "paid", "note": null, "items": [{"sku": "ABC-1", "qty": 2}], "labels": {"env": "prod"}}`,
+		},
+		{
+			name:    "missing required",
+			payload: `{"items": [{"sku": "ABC-1", "qty": 2}]}`,
+			want:    []string{"email: exists", "id: exists"},
+		},
+		{
+			name:    "wrong types and formats",
+			payload: `{"id": 5, "email": "nope", "status": "lost", "note": "too long", "items": [{"sku": "ABC-1", "qty": 2}]}`,
+			want:    []string{"email: email", "id: type", "note: max", "status: oneof"},
+		},
+		{
+			name:    "nested items through $ref",
+			payload: `{"id": "3f1e8a52-6c1d-4c3a-9f55-0f2a8b1c2d3e", "email": "a@b.co", "items": [{"sku": "abc", "qty": 1.5}, {"qty": 100}]}`,
+			want:    []string{"items[0].qty: type", "items[0].sku: pattern", "items[1].qty: lt", "items[1].sku: exists"},
+		},
+		{
+			name:    "array and map shapes",
+			payload: `{"id": "3f1e8a52-6c1d-4c3a-9f55-0f2a8b1c2d3e", "email": "a@b.co", "items": [], "labels": {"Env": 1}}`,
+			want:    []string{"items: min", "labels[Env].$key: pattern", "labels[Env]: type"},
+		},
+		{
+			name:    "wrong shape is reported, not fatal",
+			payload: `{"id": "3f1e8a52-6c1d-4c3a-9f55-0f2a8b1c2d3e", "email": "a@b.co", "items": "none", "labels": []}`,
+			want:    []string{"items: type", "labels: type"},
+		},
+		{
+			name:    "recursive root reference and unknown property",
+			payload: `{"id": "3f1e8a52-6c1d-4c3a-9f55-0f2a8b1c2d3e", "email": "a@b.co", "items": [{"sku": "ABC-1", "qty": 2}], "extra": 1, "parent": {"items": []}}`,
+			want:    []string{": allowed_keys", "parent.email: exists", "parent.id: exists", "parent.items: min"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(decode(t, tt.payload))
+			assert.Equal(t, tt.want, failures(t, err))
+		})
+	}
+}
+
+func TestImport_Null(t *testing.T) {
+	s, err := ImportJSON([]byte(`{
+		"type": "object",
+		"required": ["a"],
+		"properties": {
+			"a": {"type": ["string", "null"]},
+			"b": {"type": "string", "minLength": 2}
+		}
+	}`), nil)
+	assert.NoError(t, err)
+
+	v := validator.NewFromSchema(s)
+
+	// Required only checks that the key exists, while null is checked against type
+	tests := []struct {
+		name    string
+		payload string
+		want    []string
+	}{
+		{"required null", `{"a": null}`, nil},
+		{"optional null", `{"a": "x", "b": null}`, []string{"b: type"}},
+		{"missing", `{"b": "xy"}`, []string{"a: exists"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(decode(t, tt.payload))
+			assert.Equal(t, tt.want, failures(t, err))
+		})
+	}
+}
+
+func TestImport_Combinators(t *testing.T) {
+	s, err := ImportJSON([]byte(`{
+		"type": "object",
+		"properties": {
+			"contact": {
+				"anyOf": [
+					{"type": "string", "format": "email"},
+					{"type": "string", "pattern": "^\\+\\d+$"}
+				]
+			},
+			"id": {"oneOf": [{"type": "integer"}, {"type": "number", "minimum": 10}]},
+			"name": {"type": "string", "not": {"enum": ["root", "admin"]}},
+			"size": {"allOf": [{"minimum": 1}, {"maximum": 10}]}
+		}
+	}`), nil)
+	assert.NoError(t, err)
+
+	v := validator.NewFromSchema(s)
+
+	err = v.Validate(decode(t, `{"contact": "+123", "id": 2, "name": "bob", "size": 5}`))
+	assert.NoError(t, err)
+
+	err = v.Validate(decode(t, `{"contact": "bob", "id": 12, "name": "root", "size": 11}`))
+	assert.Equal(t, []string{"contact: anyOf", "id: oneOf", "name: not", "size: lte"}, failures(t, err))
+
+	// anyOf failures are a single error holding the errors of each branch
+	var errs schema.ValidationErrors
+	assert.True(t, errors.As(err, &errs))
+	var branches schema.BranchErrors
+	assert.True(t, errors.As(errs[0].Err, &branches))
+	assert.Len(t, branches, 2)
+	assert.True(t, branches[0].HasErrorCode("email"))
+	assert.True(t, branches[1].HasErrorCode("pattern"))
+
+	// oneOf reports how many branches passed
+	assert.Equal(t, []any{2}, errs[1].Params)
+}
+
+func TestImport_CustomRegistry(t *testing.T) {
+	r := rule.NewRegistry()
+	rule.RegisterDefault(r)
+	r.Register("slug", func(ctx *schema.Context) error {
+		for _, c := range ctx.Value().String() {
+			if (c < 'a' || c > 'z') && c != '-' {
+				return schema.ErrCheckFailed
+			}
+		}
+		return nil
+	})
+
+	im := NewImporter(r)
+	im.RegisterFormat("slug", "slug")
+
+	s, err := im.ImportJSON([]byte(`{"properties": {"slug": {"format": "slug"}}}`))
+	assert.NoError(t, err)
+
+	err = validator.NewFromSchema(s).Validate(map[string]any{"slug": "Not A Slug"})
+	assert.Equal(t, []string{"slug: slug"}, failures(t, err))
+}
+
+func TestImport_Errors(t *testing.T) {
+	_, err := ImportJSON([]byte(`{
+		"type": "object",
+		"properties": {
+			"a": {"type": "string", "contentEncoding": "base64"},
+			"b": {"format": "iri"},
+			"c": {"type": "strin"},
+			"d": {"$ref": "#/$defs/missing"},
+			"e": {"$ref": "other.json#/x"},
+			"f": {"enum": [{"x": 1}]},
+			"g": {"items": [{"type": "string"}]},
+			"h": {"type": "string", "pattern": "(["}
+		},
+		"patternProperties": {"^x-": {}}
+	}`), nil)
+
+	var errs ImportErrors
+	assert.True(t, errors.As(err, &errs))
+
+	got := make([]string, len(errs))
+	for i, e := range errs {
+		got[i] = e.Pointer + " " + e.Keyword
+	}
+	assert.ElementsMatch(t, []string{
+		"# patternProperties",
+		"#/properties/a contentEncoding",
+		"#/properties/b format",
+		"#/properties/c type",
+		"#/properties/d $ref",
+		"#/properties/e $ref",
+		"#/properties/f enum",
+		"#/properties/g items",
+		"#/properties/h pattern",
+	}, got)
+	assert.ErrorIs(t, err, ErrUnsupported)
+	assert.ErrorIs(t, err, rule.ErrInvalidParams)
+}
+
+func TestImport_CircularRef(t *testing.T) {
+	for _, doc := range []string{
+		`{"$defs": {"a": {"$ref": "#/$defs/a"}}, "$ref": "#/$defs/a"}`,
+		`{"$defs": {"a": {"$ref": "#/$defs/b"}, "b": {"$ref": "#/$defs/a"}}, "$ref": "#/$defs/a"}`,
+	} {
+		_, err := ImportJSON([]byte(doc), nil)
+
+		var errs ImportErrors
+		assert.True(t, errors.As(err, &errs), doc)
+		assert.NotEmpty(t, errs, doc)
+		for _, e := range errs {
+			assert.Equal(t, "$ref", e.Keyword, doc)
+		}
+		assert.Equal(t, "#", errs[0].Pointer, doc)
+		assert.ErrorIs(t, err, ErrInvalidKeyword, doc)
+	}
+
+	// A reference back to an enclosing schema is not a cycle of references only
+	_, err := ImportJSON([]byte(`{
+		"type": "object",
+		"properties": {"next": {"$ref": "#"}}
+	}`), nil)
+	assert.NoError(t, err)
+}
+
+func TestImport_RecursiveWalk(t *testing.T) {
+	s, err := ImportJSON([]byte(`{
+		"$defs": {"tree": {"type": "array", "items": {"$ref": "#/$defs/tree"}}},
+		"$ref": "#/$defs/tree"
+	}`), nil)
+	assert.NoError(t, err)
+
+	var paths []string
+	err = schema.Walk(s, func(path string, _ schema.Schema) error {
+		paths = append(paths, path)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"", "", "[*]"}, paths)
+}
+
+func TestImport_RoundTrip(t *testing.T) {
+	s, err := ImportJSON([]byte(importDoc), nil)
+	assert.NoError(t, err)
+
+	doc, err := Export(s)
+	assert.NoError(t, err)
+
+	// The exported document validates the same payloads
+	exported, err := Import(decode(t, mustJSON(t, doc)), nil)
+	assert.NoError(t, err)
+
+	payload := decode(t, `{"id": 5, "items": [{"sku": "abc", "qty": 0}]}`)
+	want := validator.NewFromSchema(s).Validate(payload)
+	got := validator.NewFromSchema(exported).Validate(payload)
+	assert.Equal(t, failures(t, want), failures(t, got))
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
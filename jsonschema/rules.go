@@ -0,0 +1,297 @@
+package jsonschema
+
+import (
+	"regexp"
+
+	"github.com/spf13/cast"
+	"github.com/weilence/schema-validator/rule"
+	"github.com/weilence/schema-validator/schema"
+)
+
+// requiredMarker flags a node as required for its parent object, which moves it into its required list
+const requiredMarker = "\x00required"
+
+func registerDefault(e *Exporter) {
+	e.Register("required", func(node map[string]any, kind Kind, v schema.Validator) {
+		node[requiredMarker] = true
+	})
+
+	e.Register("present", func(node map[string]any, kind Kind, v schema.Validator) {
+		node[requiredMarker] = true
+	})
+
+	e.Register("exists", func(node map[string]any, kind Kind, v schema.Validator) {
+		node[requiredMarker] = true
+	})
+
+	// omitempty, omitnil and omitabsent only control when the other rules run
+	e.Register("omitempty", func(node map[string]any, kind Kind, v schema.Validator) {})
+	e.Register("omitnil", func(node map[string]any, kind Kind, v schema.Validator) {})
+	e.Register("omitabsent", func(node map[string]any, kind Kind, v schema.Validator) {})
+
+	// when rules read sibling fields, which the schema of a property can't refer to, and their
+	// params hold Go conditions, so they are left out; object conditionals export as if/then/else
+	e.Register(rule.WhenName, func(node map[string]any, kind Kind, v schema.Validator) {})
+
+	e.Register("allowed_keys", func(node map[string]any, kind Kind, v schema.Validator) {
+		if kind == KindObject {
+			node["additionalProperties"] = false
+			return
+		}
+
+		node[ExtensionPrefix+v.Name()] = extensionValue(v.Params())
+	})
+
+	e.Register(rule.AnyOfName, combinatorMapper(e, "anyOf"))
+	e.Register(rule.AllOfName, combinatorMapper(e, "allOf"))
+	e.Register(rule.NotName, func(node map[string]any, kind Kind, v schema.Validator) {
+		validators, ok := validatorParams(v)
+		if !ok {
+			node[ExtensionPrefix+v.Name()] = extensionValue(v.Params())
+			return
+		}
+
+		branch := make(map[string]any)
+		e.validators(branch, kind, validators)
+		node["not"] = standaloneRequired(branch)
+	})
+
+	e.Register("type", func(node map[string]any, kind Kind, v schema.Validator) {
+		params := v.Params()
+		if len(params) != 1 {
+			node[ExtensionPrefix+v.Name()] = extensionValue(params)
+			return
+		}
+
+		types, err := cast.ToStringSliceE(params[0])
+		if err != nil || len(types) == 0 {
+			node[ExtensionPrefix+v.Name()] = extensionValue(params)
+			return
+		}
+
+		if len(types) == 1 {
+			node["type"] = types[0]
+		} else {
+			node["type"] = types
+		}
+	})
+
+	e.Register("min", boundMapper(0, "minimum", "minLength", "minItems", "minProperties"))
+	e.Register("max", boundMapper(0, "maximum", "maxLength", "maxItems", "maxProperties"))
+	e.Register("gte", boundMapper(0, "minimum", "minLength", "minItems", "minProperties"))
+	e.Register("lte", boundMapper(0, "maximum", "maxLength", "maxItems", "maxProperties"))
+	e.Register("gt", boundMapper(1, "exclusiveMinimum", "minLength", "minItems", "minProperties"))
+	e.Register("lt", boundMapper(-1, "exclusiveMaximum", "maxLength", "maxItems", "maxProperties"))
+
+	e.Register("len", func(node map[string]any, kind Kind, v schema.Validator) {
+		n, ok := integerParam(v)
+		if !ok {
+			node[ExtensionPrefix+v.Name()] = extensionValue(v.Params())
+			return
+		}
+
+		switch kind {
+		case KindArray:
+			node["minItems"], node["maxItems"] = n, n
+		case KindMap, KindObject:
+			node["minProperties"], node["maxProperties"] = n, n
+		default:
+			node["minLength"], node["maxLength"] = n, n
+		}
+	})
+
+	e.Register("oneof", func(node map[string]any, kind Kind, v schema.Validator) {
+		params := v.Params()
+		if len(params) != 1 {
+			node[ExtensionPrefix+v.Name()] = extensionValue(params)
+			return
+		}
+
+		values, err := cast.ToStringSliceE(params[0])
+		if err != nil {
+			node[ExtensionPrefix+v.Name()] = extensionValue(params)
+			return
+		}
+
+		enum := make([]any, len(values))
+		for i, value := range values {
+			enum[i] = value
+		}
+		node["enum"] = enum
+	})
+
+	for name, format := range map[string]string{
+		"email":            "email",
+		"uri":              "uri",
+		"url":              "uri",
+		"uuid":             "uuid",
+		"uuid_rfc4122":     "uuid",
+		"ipv4":             "ipv4",
+		"ip4_addr":         "ipv4",
+		"ipv6":             "ipv6",
+		"ip6_addr":         "ipv6",
+		"hostname":         "hostname",
+		"hostname_rfc1123": "hostname",
+		"datetime":         "date-time",
+	} {
+		e.Register(name, formatMapper(format))
+	}
+
+	for name, pattern := range map[string]string{
+		"e164":             `^\+[1-9]\d{1,14}$`,
+		"hexadecimal":      `^[0-9a-fA-F]+$`,
+		"hexcolor":         `^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`,
+		"iso3166_1_alpha2": `^[A-Z]{2}$`,
+		"iso3166_1_alpha3": `^[A-Z]{3}$`,
+		"iso4217":          `^[A-Z]{3}$`,
+		"ulid":             `^[0-9A-HJKMNP-TV-Z]{26}$`,
+	} {
+		e.Register(name, patternMapper(pattern))
+	}
+
+	e.Register("startswith", stringPatternMapper(func(s string) string { return "^" + regexp.QuoteMeta(s) }))
+	e.Register("endswith", stringPatternMapper(func(s string) string { return regexp.QuoteMeta(s) + "$" }))
+	e.Register("contains", stringPatternMapper(regexp.QuoteMeta))
+	e.Register("pattern", stringPatternMapper(func(s string) string { return s }))
+}
+
+// combinatorMapper maps a combinator validator to a list of subschemas, one per combined validator
+func combinatorMapper(e *Exporter, keyword string) RuleMapper {
+	return func(node map[string]any, kind Kind, v schema.Validator) {
+		validators, ok := validatorParams(v)
+		if !ok {
+			node[ExtensionPrefix+v.Name()] = extensionValue(v.Params())
+			return
+		}
+
+		branches, _ := node[keyword].([]any)
+		for _, child := range validators {
+			branch := make(map[string]any)
+			e.validators(branch, kind, []schema.Validator{child})
+			branches = append(branches, standaloneRequired(branch))
+		}
+		node[keyword] = branches
+	}
+}
+
+// validatorParams returns the params of a combinator validator
+func validatorParams(v schema.Validator) ([]schema.Validator, bool) {
+	validators := make([]schema.Validator, 0, len(v.Params()))
+	for _, param := range v.Params() {
+		child, ok := param.(schema.Validator)
+		if !ok {
+			return nil, false
+		}
+		validators = append(validators, child)
+	}
+
+	return validators, true
+}
+
+// standaloneRequired turns the required marker of a subschema into an extension keyword,
+// as a subschema has no parent object to list it in
+func standaloneRequired(node map[string]any) map[string]any {
+	if _, ok := node[requiredMarker]; ok {
+		delete(node, requiredMarker)
+		node[ExtensionPrefix+"required"] = true
+	}
+
+	return node
+}
+
+// boundMapper maps a numeric bound to the keyword matching the schema kind
+// lengthOffset adjusts exclusive bounds to the inclusive length keywords
+func boundMapper(lengthOffset int64, numberKeyword, lengthKeyword, itemsKeyword, propertiesKeyword string) RuleMapper {
+	return func(node map[string]any, kind Kind, v schema.Validator) {
+		params := v.Params()
+		if len(params) != 1 {
+			node[ExtensionPrefix+v.Name()] = extensionValue(params)
+			return
+		}
+
+		n, isInt := integerParam(v)
+		switch kind {
+		case KindArray, KindMap, KindObject:
+			if !isInt {
+				node[ExtensionPrefix+v.Name()] = params[0]
+				return
+			}
+
+			keyword := itemsKeyword
+			if kind != KindArray {
+				keyword = propertiesKeyword
+			}
+			node[keyword] = max(n+lengthOffset, 0)
+		default:
+			// Field rules compare numbers by value and strings by length, so both keywords apply
+			number, err := cast.ToFloat64E(params[0])
+			if err != nil {
+				node[ExtensionPrefix+v.Name()] = params[0]
+				return
+			}
+
+			if isInt {
+				node[numberKeyword] = n
+				node[lengthKeyword] = max(n+lengthOffset, 0)
+			} else {
+				node[numberKeyword] = number
+			}
+		}
+	}
+}
+
+func formatMapper(format string) RuleMapper {
+	return func(node map[string]any, kind Kind, v schema.Validator) {
+		node["format"] = format
+	}
+}
+
+func patternMapper(pattern string) RuleMapper {
+	return func(node map[string]any, kind Kind, v schema.Validator) {
+		addPattern(node, pattern)
+	}
+}
+
+func stringPatternMapper(toPattern func(string) string) RuleMapper {
+	return func(node map[string]any, kind Kind, v schema.Validator) {
+		params := v.Params()
+		if len(params) != 1 {
+			node[ExtensionPrefix+v.Name()] = extensionValue(params)
+			return
+		}
+
+		addPattern(node, toPattern(cast.ToString(params[0])))
+	}
+}
+
+// addPattern sets the pattern keyword, moving further patterns into allOf
+// as a schema can only have a single pattern
+func addPattern(node map[string]any, pattern string) {
+	if _, ok := node["pattern"]; !ok {
+		node["pattern"] = pattern
+		return
+	}
+
+	allOf, _ := node["allOf"].([]any)
+	node["allOf"] = append(allOf, map[string]any{"pattern": pattern})
+}
+
+// integerParam returns the single param of v as an integer
+func integerParam(v schema.Validator) (int64, bool) {
+	params := v.Params()
+	if len(params) != 1 {
+		return 0, false
+	}
+
+	switch p := params[0].(type) {
+	case float32, float64:
+		f := cast.ToFloat64(p)
+		if f != float64(int64(f)) {
+			return 0, false
+		}
+		return int64(f), true
+	default:
+		n, err := cast.ToInt64E(p)
+		return n, err == nil
+	}
+}
@@ -1,331 +1,101 @@
 package validator
 
 import (
-	"fmt"
+	"io/fs"
 	"reflect"
-	"slices"
-	"strings"
-	"time"
 
+	"github.com/weilence/schema-validator/builder"
 	"github.com/weilence/schema-validator/rule"
 	"github.com/weilence/schema-validator/schema"
 	"github.com/weilence/schema-validator/tag"
 )
 
-type ParseConfig struct {
-	Registry   *rule.Registry
-	TagParser  *tag.Parser
-	DiveTag    string
-	ValueTypes []reflect.Type
-}
+// ParseConfig configures how struct tags are compiled into schemas, see builder.ParseConfig
+type ParseConfig = builder.ParseConfig
 
-func defaultParseConfig() *ParseConfig {
-	return &ParseConfig{
-		Registry:   rule.DefaultRegistry(),
-		TagParser:  tag.NewParser(tag.DefaultConfig()),
-		DiveTag:    "dive",
-		ValueTypes: []reflect.Type{reflect.TypeFor[time.Time]()},
-	}
-}
+// ParseOption configures a ParseConfig
+type ParseOption = builder.ParseOption
+
+// TypeHandler builds the schema for fields of a specific Go type, see builder.TypeHandler
+type TypeHandler = builder.TypeHandler
 
-type ParseOption func(*ParseConfig)
+// NameFunc resolves the schema field name of a struct field, see builder.NameFunc
+type NameFunc = builder.NameFunc
 
 func WithRegistry(registry *rule.Registry) ParseOption {
-	return func(cfg *ParseConfig) {
-		cfg.Registry = registry
-	}
+	return builder.WithRegistry(registry)
 }
 
 func WithTagParser(parser *tag.Parser) ParseOption {
-	return func(cfg *ParseConfig) {
-		cfg.TagParser = parser
-	}
+	return builder.WithTagParser(parser)
 }
 
-func WithDiveTag(diveTag string) ParseOption {
-	return func(cfg *ParseConfig) {
-		cfg.DiveTag = diveTag
-	}
+func WithTagConfig(tagCfg tag.Config) ParseOption {
+	return builder.WithTagConfig(tagCfg)
 }
 
-func WithValueTypes(types ...reflect.Type) ParseOption {
-	return func(cfg *ParseConfig) {
-		cfg.ValueTypes = append(cfg.ValueTypes, types...)
-	}
+func WithDiveTag(diveTag string) ParseOption {
+	return builder.WithDiveTag(diveTag)
 }
 
-// Parse parses a struct type into an ObjectSchema using struct tags
-func Parse(rt reflect.Type, opts ...ParseOption) (*schema.ObjectSchema, error) {
-	cfg := defaultParseConfig()
-	for _, opt := range opts {
-		opt(cfg)
-	}
-
-	return parse(rt, cfg)
+// WithKeysTags sets the keywords enclosing the rules for map keys
+func WithKeysTags(keysTag, endKeysTag string) ParseOption {
+	return builder.WithKeysTags(keysTag, endKeysTag)
 }
 
-func parse(rt reflect.Type, cfg *ParseConfig) (*schema.ObjectSchema, error) {
-	if rt.Kind() == reflect.Pointer {
-		rt = rt.Elem()
-	}
-
-	objSchema := schema.NewObject()
-	for i := 0; i < rt.NumField(); i++ {
-		field := rt.Field(i)
-		if err := parseStructField(objSchema, field, cfg); err != nil {
-			return nil, err
-		}
-	}
-
-	return objSchema, nil
+func WithValueTypes(types ...reflect.Type) ParseOption {
+	return builder.WithValueTypes(types...)
 }
 
-func parseStructField(s *schema.ObjectSchema, field reflect.StructField, cfg *ParseConfig) error {
-	fieldType := field.Type
-	if fieldType.Kind() == reflect.Pointer {
-		fieldType = fieldType.Elem()
-	}
-
-	if field.Anonymous {
-		if fieldType.Kind() != reflect.Struct {
-			return nil
-		}
-
-		for i := 0; i < fieldType.NumField(); i++ {
-			embeddedField := fieldType.Field(i)
-			if err := parseStructField(s, embeddedField, cfg); err != nil {
-				return err
-			}
-		}
-
-		return nil
-	}
-
-	if !field.IsExported() {
-		return nil
-	}
-
-	fieldName := getFieldName(field)
-	validateTag := field.Tag.Get("validate")
-	if validateTag == "-" {
-		return nil
-	}
-
-	rules := cfg.TagParser.Parse(validateTag)
-	fieldSchema, err := parseField(field.Type, rules, cfg)
-	if err != nil {
-		return err
-	}
-
-	s.AddField(fieldName, fieldSchema).AddFieldName(fieldName, field.Name)
-	return nil
+// WithTypeHandler registers a handler that builds the schema for fields of type rt
+func WithTypeHandler(rt reflect.Type, handler TypeHandler) ParseOption {
+	return builder.WithTypeHandler(rt, handler)
 }
 
-func parseField(fieldType reflect.Type, rules []tag.Rule, cfg *ParseConfig) (schema.Schema, error) {
-	if fieldType.Kind() == reflect.Pointer {
-		fieldType = fieldType.Elem()
-	}
-
-	if fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array {
-		diveIdx := slices.IndexFunc(rules, func(r tag.Rule) bool { return r.Name == cfg.DiveTag })
-		var arrayRules, itemRules []tag.Rule
-		if diveIdx >= 0 {
-			arrayRules = rules[:diveIdx]
-			itemRules = rules[diveIdx+1:]
-		} else {
-			arrayRules = rules
-		}
-
-		elemSchema, err := parseField(fieldType.Elem(), itemRules, cfg)
-		if err != nil {
-			return nil, err
-		}
-
-		arraySchema := schema.NewArray(elemSchema)
-		for _, rule := range arrayRules {
-			params := convertValidatorParams(rule.Name, rule.Params, cfg)
-			v := cfg.Registry.NewValidator(rule.Name, params...)
-			if v != nil {
-				arraySchema.AddValidator(v)
-			}
-		}
-
-		return arraySchema, nil
-	}
-
-	if fieldType.Kind() == reflect.Struct && !slices.Contains(cfg.ValueTypes, fieldType) {
-		return parse(fieldType, cfg)
-	}
-
-	if fieldType.Kind() == reflect.Map {
-		return schema.NewObject(), nil
-	}
-
-	fieldSchema := schema.NewField()
-	for _, rule := range rules {
-		params := convertValidatorParams(rule.Name, rule.Params, cfg)
-		v := cfg.Registry.NewValidator(rule.Name, params...)
-		if v != nil {
-			fieldSchema.AddValidator(v)
-		}
-	}
-
-	return fieldSchema, nil
+// WithNameFunc sets how schema field names are resolved from struct fields
+func WithNameFunc(fn NameFunc) ParseOption {
+	return builder.WithNameFunc(fn)
 }
 
-func convertValidatorParams(name string, paramStrs []string, cfg *ParseConfig) []any {
-	paramTypes := cfg.Registry.GetValidatorParamTypes(name)
-
-	paramTypesLen := len(paramTypes)
-	if paramTypesLen == 0 && len(paramStrs) != 0 {
-		panic(fmt.Sprintf("%s does not take any parameters", name))
-	}
-
-	if paramTypesLen == 1 {
-		paramType := paramTypes[0]
-		kind := paramType.Kind()
-		switch kind {
-		case reflect.Array:
-			res := reflect.ArrayOf(paramType.Len(), paramType.Elem())
-			rv := reflect.New(res).Elem()
-			for i, paramStr := range paramStrs {
-				elem := parseValidatorParam(paramType.Elem(), paramStr)
-				rv.Index(i).Set(reflect.ValueOf(elem))
-			}
-			return []any{rv.Interface()}
-		case reflect.Slice:
-			res := reflect.MakeSlice(paramType, 0, 0)
-			for _, paramStr := range paramStrs {
-				elem := parseValidatorParam(paramType.Elem(), paramStr)
-				res = reflect.Append(res, reflect.ValueOf(elem))
-			}
-			return []any{res.Interface()}
-		default:
-			if len(paramStrs) != 1 {
-				panic(fmt.Sprintf("%s expected 1 parameter, got %d", name, len(paramStrs)))
-			}
-			return []any{parseValidatorParam(paramType, paramStrs[0])}
-		}
-	}
-
-	if len(paramStrs) != paramTypesLen {
-		panic(fmt.Sprintf("%s expected %d parameters, got %d", name, paramTypesLen, len(paramStrs)))
-	}
+// WithTypeDiveTag sets the dive keyword for a specific slice or array type
+func WithTypeDiveTag(rt reflect.Type, diveTag string) ParseOption {
+	return builder.WithTypeDiveTag(rt, diveTag)
+}
 
-	params := make([]any, paramTypesLen)
-	for i, paramType := range paramTypes {
-		params[i] = parseValidatorParam(paramType, paramStrs[i])
-	}
+// WithGroupInheritance makes the validation group inherit the rules of parents
+func WithGroupInheritance(group string, parents ...string) ParseOption {
+	return builder.WithGroupInheritance(group, parents...)
+}
 
-	return params
+// Parse parses a struct type into an ObjectSchema using struct tags
+func Parse(rt reflect.Type, opts ...ParseOption) (*schema.ObjectSchema, error) {
+	return builder.Parse(rt, opts...)
 }
 
-func parseValidatorParam(paramType reflect.Type, paramValue string) any {
-	switch paramType.Kind() {
-	case reflect.Bool:
-		var v bool
-		if _, err := fmt.Sscanf(paramValue, "%t", &v); err != nil {
-			panic(fmt.Sprintf("invalid bool parameter: %s", paramValue))
-		}
-		return v
-	case reflect.Int:
-		var v int
-		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid int parameter: %s", paramValue))
-		}
-		return v
-	case reflect.Int8:
-		var v int8
-		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid int8 parameter: %s", paramValue))
-		}
-		return v
-	case reflect.Int16:
-		var v int16
-		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid int16 parameter: %s", paramValue))
-		}
-		return v
-	case reflect.Int32:
-		var v int32
-		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid int32 parameter: %s", paramValue))
-		}
-		return v
-	case reflect.Int64:
-		var v int64
-		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid int64 parameter: %s", paramValue))
-		}
-		return v
-	case reflect.Uint:
-		var v uint
-		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid uint parameter: %s", paramValue))
-		}
-		return v
-	case reflect.Uint8:
-		var v uint8
-		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid uint8 parameter: %s", paramValue))
-		}
-		return v
-	case reflect.Uint16:
-		var v uint16
-		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid uint16 parameter: %s", paramValue))
-		}
-		return v
-	case reflect.Uint32:
-		var v uint32
-		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid uint32 parameter: %s", paramValue))
-		}
-		return v
-	case reflect.Uint64:
-		var v uint64
-		if _, err := fmt.Sscanf(paramValue, "%d", &v); err != nil {
-			panic(fmt.Sprintf("invalid uint64 parameter: %s", paramValue))
-		}
-		return v
-	case reflect.Float32:
-		var v float32
-		if _, err := fmt.Sscanf(paramValue, "%f", &v); err != nil {
-			panic(fmt.Sprintf("invalid float32 parameter: %s", paramValue))
-		}
-		return v
-	case reflect.Float64:
-		var v float64
-		if _, err := fmt.Sscanf(paramValue, "%f", &v); err != nil {
-			panic(fmt.Sprintf("invalid float64 parameter: %s", paramValue))
-		}
-		return v
-	case reflect.String, reflect.Interface:
-		return paramValue
-	default:
-		panic(fmt.Sprintf("unsupported parameter type: %s", paramType.Kind()))
-	}
+// WithImplementations registers concrete types of the interface type iface, whose fields
+// validate according to their dynamic type
+func WithImplementations(iface reflect.Type, impls ...reflect.Type) ParseOption {
+	return builder.WithImplementations(iface, impls...)
 }
 
-func getFieldName(field reflect.StructField) string {
-	if name := extractNameFromTag(field.Tag.Get("json")); name != "" {
-		return name
-	}
-	if name := extractNameFromTag(field.Tag.Get("param")); name != "" {
-		return name
-	}
-	if name := extractNameFromTag(field.Tag.Get("query")); name != "" {
-		return name
+// LoadSchema compiles a YAML or JSON schema file in fsys with the rules of registry,
+// nil for the default registry. See builder.LoadSchema for the file format
+func LoadSchema(fsys fs.FS, name string, registry *rule.Registry) (schema.Schema, error) {
+	var opts []ParseOption
+	if registry != nil {
+		opts = append(opts, WithRegistry(registry))
 	}
-	return field.Name
+
+	return builder.LoadSchema(fsys, name, opts...)
 }
 
-func extractNameFromTag(t string) string {
-	if t == "" || t == "-" {
-		return ""
-	}
-	if idx := strings.Index(t, ","); idx != -1 {
-		return t[:idx]
+// CompileRules compiles a flat map of paths such as "items.*.qty" to rule strings into
+// a schema with the rules of registry, nil for the default registry. See builder.CompileRules
+func CompileRules(rules map[string]string, registry *rule.Registry) (schema.Schema, error) {
+	var opts []ParseOption
+	if registry != nil {
+		opts = append(opts, WithRegistry(registry))
 	}
-	return t
+
+	return builder.CompileRules(rules, opts...)
 }
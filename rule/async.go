@@ -0,0 +1,135 @@
+package rule
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/weilence/schema-validator/schema"
+)
+
+// Loader loads the results of an async rule for many values at once, such as with a single
+// query for which of the emails are already taken
+type Loader interface {
+	// Load reports whether each of values passes the rule with params, in order
+	Load(ctx context.Context, params []any, values []any) ([]bool, error)
+}
+
+// LoaderFunc adapts a function to a Loader
+type LoaderFunc func(ctx context.Context, params []any, values []any) ([]bool, error)
+
+func (f LoaderFunc) Load(ctx context.Context, params []any, values []any) ([]bool, error) {
+	return f(ctx, params, values)
+}
+
+// asyncRule is a rule registered with RegisterAsync
+type asyncRule struct {
+	code    string
+	loader  Loader
+	timeout time.Duration
+
+	batches sync.Map // batchKey(params) -> *asyncBatch
+}
+
+// batch returns the batch of the rule with params, shared by every field using them
+func (r *asyncRule) batch(params []any) *asyncBatch {
+	key := batchKey(params)
+	if b, ok := r.batches.Load(key); ok {
+		return b.(*asyncBatch)
+	}
+
+	b, _ := r.batches.LoadOrStore(key, &asyncBatch{rule: r, params: params})
+	return b.(*asyncBatch)
+}
+
+// batchKey encodes params so that different params get different keys, each param being
+// written with its type and prefixed by its length, unlike fmt.Sprint which prints
+// []any{"a b"} and []any{"a", "b"} the same
+func batchKey(params []any) string {
+	var sb strings.Builder
+	for _, param := range params {
+		s := fmt.Sprintf("%T:%#v", param, param)
+		fmt.Fprintf(&sb, "%d:%s", len(s), s)
+	}
+
+	return sb.String()
+}
+
+// asyncBatch implements schema.Batch, loading the values of a rule with the same params
+type asyncBatch struct {
+	rule   *asyncRule
+	params []any
+}
+
+func (b *asyncBatch) Name() string {
+	return b.rule.code
+}
+
+func (b *asyncBatch) Params() []any {
+	return b.params
+}
+
+// Resolve calls the loader, giving up once the timeout of the rule passes even if the loader
+// doesn't return
+func (b *asyncBatch) Resolve(ctx context.Context, values []any) ([]bool, error) {
+	if b.rule.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.rule.timeout)
+		defer cancel()
+	}
+
+	type result struct {
+		passed []bool
+		err    error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{err: fmt.Errorf("loader panic: name=%s, params=%v, err=%v", b.rule.code, b.params, r)}
+			}
+		}()
+
+		passed, err := b.rule.loader.Load(ctx, b.params, values)
+		done <- result{passed: passed, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.passed, r.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("loader of %d values: %w", len(values), ctx.Err())
+	}
+}
+
+// RegisterAsync registers a rule whose checks need I/O, such as uniqueness or existence checks
+// Rather than running inline, checks of non-empty values are deferred during traversal, and
+// once it completes the distinct values checked by the rule with the same params, across
+// every field and element, are passed to loader in a single call bounded by timeout,
+// unless it is zero. Failed checks are reported at the paths of their values
+// Checks inside anyOf, oneOf and not are instead resolved for each branch before its result is decided
+// Params are passed to loader as written, e.g. ["products"] for exists=products
+func (r *Registry) RegisterAsync(code string, loader Loader, timeout time.Duration) {
+	rule := &asyncRule{code: code, loader: loader, timeout: timeout}
+
+	r.validators[code] = validatorFactory{
+		name: code,
+		fn: func(ctx *schema.Context, params []any) error {
+			v := ctx.Value()
+			if v.IsNilOrZero() {
+				return nil
+			}
+
+			ctx.Defer(rule.batch(params), v.Any())
+			return nil
+		},
+		raw: true,
+	}
+}
+
+func RegisterAsync(code string, loader Loader, timeout time.Duration) {
+	defaultRegistry.RegisterAsync(code, loader, timeout)
+}
@@ -0,0 +1,140 @@
+package rule
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weilence/schema-validator/data"
+	"github.com/weilence/schema-validator/schema"
+)
+
+// memoryLoader is an in-memory stand-in for a database, recording the batches it loads
+type memoryLoader struct {
+	mu      sync.Mutex
+	tables  map[string][]any
+	batches [][]any
+}
+
+func (l *memoryLoader) Load(ctx context.Context, params []any, values []any) ([]bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.batches = append(l.batches, values)
+	rows := l.tables[params[0].(string)]
+	passed := make([]bool, len(values))
+	for i, v := range values {
+		passed[i] = slices.Contains(rows, v)
+	}
+
+	return passed, nil
+}
+
+func TestRegisterAsync(t *testing.T) {
+	loader := &memoryLoader{tables: map[string][]any{"products": {"a", "b"}}}
+	r := NewRegistry()
+	r.RegisterAsync("exists", loader, time.Second)
+
+	s := schema.NewObject().
+		AddField("main", schema.NewField().AddValidator(r.NewValidator("exists", "products"))).
+		AddField("items", schema.NewArray(schema.NewField().AddValidator(r.NewValidator("exists", "products"))))
+
+	ctx := schema.NewContext(s, data.New(map[string]any{
+		"main":  "x",
+		"items": []any{"a", "x", "", "b", "y", "a"},
+	}))
+	require.NoError(t, s.Validate(ctx))
+	assert.Empty(t, ctx.Errors(), "checks are deferred")
+
+	require.NoError(t, ctx.ResolveDeferred())
+	assert.Equal(t, "main: exists [products]\nitems[1]: exists [products]\nitems[4]: exists [products]", ctx.Errors().Error())
+
+	// Both fields share a batch, the empty value is skipped and duplicates are loaded once
+	assert.Equal(t, [][]any{{"x", "a", "b", "y"}}, loader.batches)
+}
+
+func TestRegisterAsync_Timeout(t *testing.T) {
+	blocked := make(chan struct{})
+	defer close(blocked)
+
+	r := NewRegistry()
+	r.RegisterAsync("unique", LoaderFunc(func(ctx context.Context, params []any, values []any) ([]bool, error) {
+		<-blocked
+		return nil, nil
+	}), 10*time.Millisecond)
+
+	s := schema.NewArray(schema.NewField().AddValidator(r.NewValidator("unique")))
+	ctx := schema.NewContext(s, data.New([]string{"a", "b"}))
+	require.NoError(t, s.Validate(ctx))
+
+	err := ctx.ResolveDeferred()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var validationErr schema.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "[0]", validationErr.Path)
+	assert.Equal(t, "unique", validationErr.Code)
+}
+
+func TestRegisterAsync_Canceled(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterAsync("unique", LoaderFunc(func(ctx context.Context, params []any, values []any) ([]bool, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}), time.Second)
+
+	s := schema.NewArray(schema.NewField().AddValidator(r.NewValidator("unique")))
+	ctx := schema.NewContext(s, data.New([]string{"a", "b"}))
+	ctx.SetCollectInternal(true)
+
+	deadline, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ctx.SetContext(deadline)
+	require.NoError(t, s.Validate(ctx))
+
+	// The validation being canceled stops it rather than being collected as an internal error
+	err := ctx.ResolveDeferred()
+	assert.ErrorIs(t, err, schema.ErrCanceled)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, ctx.Errors())
+}
+
+func TestRegisterAsync_Params(t *testing.T) {
+	var loaded [][]any
+	r := NewRegistry()
+	r.RegisterAsync("exists", LoaderFunc(func(ctx context.Context, params []any, values []any) ([]bool, error) {
+		loaded = append(loaded, append(slices.Clone(params), values...))
+		return make([]bool, len(values)), nil
+	}), time.Second)
+
+	// Params printing the same are still loaded separately
+	s := schema.NewObject().
+		AddField("joined", schema.NewField().AddValidator(r.NewValidator("exists", "a b"))).
+		AddField("split", schema.NewField().AddValidator(r.NewValidator("exists", "a", "b"))).
+		AddField("int", schema.NewField().AddValidator(r.NewValidator("exists", 1))).
+		AddField("string", schema.NewField().AddValidator(r.NewValidator("exists", "1")))
+
+	ctx := schema.NewContext(s, data.New(map[string]any{"joined": "w", "split": "x", "int": "y", "string": "z"}))
+	require.NoError(t, s.Validate(ctx))
+	require.NoError(t, ctx.ResolveDeferred())
+	assert.Equal(t, [][]any{{"a b", "w"}, {"a", "b", "x"}, {1, "y"}, {"1", "z"}}, loaded)
+}
+
+type sliceBatch []string
+
+func (b sliceBatch) Name() string  { return "slice" }
+func (b sliceBatch) Params() []any { return nil }
+func (b sliceBatch) Resolve(ctx context.Context, values []any) ([]bool, error) {
+	return make([]bool, len(values)), nil
+}
+
+func TestDefer_NotComparable(t *testing.T) {
+	ctx := schema.NewContext(schema.NewField(), data.New("a"))
+	assert.PanicsWithValue(t, "batch of type rule.sliceBatch is not comparable", func() {
+		ctx.Defer(sliceBatch{"a"}, "a")
+	})
+}
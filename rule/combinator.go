@@ -0,0 +1,66 @@
+package rule
+
+import "github.com/weilence/schema-validator/schema"
+
+// Combinator names, as used in tags such as anyOf(hexcolor|rgb)
+const (
+	AnyOfName = "anyOf"
+	AllOfName = "allOf"
+	NotName   = "not"
+)
+
+// combinator is a validator combining other validators through a composite schema,
+// so its failures are reported the same way as those of composite schemas
+type combinator struct {
+	composite *schema.CompositeSchema
+	children  []schema.Validator
+}
+
+func (c combinator) Name() string {
+	return c.composite.Mode().String()
+}
+
+// Params returns the combined validators
+func (c combinator) Params() []any {
+	params := make([]any, len(c.children))
+	for i, child := range c.children {
+		params[i] = child
+	}
+
+	return params
+}
+
+func (c combinator) Validate(ctx *schema.Context) error {
+	return c.composite.Validate(ctx)
+}
+
+// AnyOf returns a validator passing when any of validators passes
+// A failure is reported as a single anyOf error whose Err is a schema.BranchErrors with the errors of each validator
+func AnyOf(validators ...schema.Validator) schema.Validator {
+	branches := make([]schema.Schema, len(validators))
+	for i, v := range validators {
+		branches[i] = schema.NewField().AddValidator(v)
+	}
+
+	return combinator{composite: schema.NewAnyOf(branches...), children: validators}
+}
+
+// AllOf returns a validator passing only when all of validators pass, reporting their errors as is
+func AllOf(validators ...schema.Validator) schema.Validator {
+	branch := schema.NewField()
+	for _, v := range validators {
+		branch.AddValidator(v)
+	}
+
+	return combinator{composite: schema.NewAllOf(branch), children: validators}
+}
+
+// Not returns a validator passing when validators don't all pass, reported as a not error otherwise
+func Not(validators ...schema.Validator) schema.Validator {
+	branch := schema.NewField()
+	for _, v := range validators {
+		branch.AddValidator(v)
+	}
+
+	return combinator{composite: schema.NewNot(branch), children: validators}
+}
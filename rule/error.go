@@ -0,0 +1,90 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned when a rule name is not registered
+	ErrNotFound = errors.New("validator not found in registry")
+
+	// ErrInvalidParams is returned when rule parameters don't match the validator signature
+	ErrInvalidParams = errors.New("invalid validator parameters")
+
+	// ErrInvalidSyntax is returned when rules are combined in an invalid way, e.g. unbalanced keywords
+	ErrInvalidSyntax = errors.New("invalid rule syntax")
+)
+
+// BuildError describes a rule that could not be compiled into a validator
+type BuildError struct {
+	// Type is the struct type declaring the rule, nil for code-built schemas
+	Type reflect.Type
+
+	// Field is the Go field name declaring the rule, empty for code-built schemas
+	Field string
+
+	// Path is the path declaring the rule in rule maps, see builder.CompileRules
+	Path string
+
+	// Rule is the rule name as written in the tag or passed to the builder
+	Rule string
+
+	Err error
+}
+
+func (e BuildError) Unwrap() error {
+	return e.Err
+}
+
+// Error implements the error interface
+func (e BuildError) Error() string {
+	var sb strings.Builder
+	if e.Type != nil {
+		sb.WriteString(e.Type.String())
+		if e.Field != "" {
+			sb.WriteString(".")
+			sb.WriteString(e.Field)
+		}
+		sb.WriteString(": ")
+	}
+	if e.Path != "" {
+		sb.WriteString(e.Path)
+		sb.WriteString(": ")
+	}
+
+	if e.Rule == "" {
+		fmt.Fprint(&sb, e.Err)
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "rule %q: %v", e.Rule, e.Err)
+	return sb.String()
+}
+
+// BuildErrors holds every rule that failed to compile
+type BuildErrors []BuildError
+
+func (r BuildErrors) Unwrap() []error {
+	if len(r) == 0 {
+		return nil
+	}
+
+	errs := make([]error, len(r))
+	for i, err := range r {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+func (r BuildErrors) Error() string {
+	errs := r.Unwrap()
+	if len(errs) == 0 {
+		return ""
+	}
+
+	return errors.Join(errs...).Error()
+}
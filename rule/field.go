@@ -1,15 +1,42 @@
 package rule
 
 import (
+	"fmt"
 	"strings"
 
+	"github.com/weilence/schema-validator/data"
+	"github.com/weilence/schema-validator/expr"
 	"github.com/weilence/schema-validator/schema"
 )
 
+// ExprValuePath is the name of the validated value in expressions of the expr rule
+const ExprValuePath = "$value"
+
+// fieldValue returns the value at a path relative to the object holding the validated value,
+// such as sibling, nested.field, items[0].price, ../field or $root.limits.max
+// Missing keys, indexes out of bounds and nil values along the path return an absent value
+func fieldValue(ctx *schema.Context, path string) (*data.Value, error) {
+	scope := ctx
+	if scope.Parent() != nil {
+		scope = scope.Parent()
+	}
+
+	v, err := scope.Lookup(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get field '%s': %w", path, err)
+	}
+
+	if v == nil {
+		return data.NewValue(nil), nil
+	}
+
+	return v, nil
+}
+
 func compareFieldValidator(ct compareType) func(*schema.Context, string) error {
 	return func(ctx *schema.Context, fieldName string) error {
 		currentValue := ctx.Value()
-		otherValue, err := ctx.Parent().GetValue(fieldName)
+		otherValue, err := fieldValue(ctx, fieldName)
 		if err != nil {
 			return err
 		}
@@ -27,6 +54,30 @@ func compareFieldValidator(ct compareType) func(*schema.Context, string) error {
 	}
 }
 
+// exprEnv resolves the paths of expressions relative to the validated object, or to the
+// object holding the validated value for other schemas, so that names refer to siblings
+type exprEnv struct {
+	ctx *schema.Context
+}
+
+func (e exprEnv) Lookup(path string) (any, error) {
+	if path == ExprValuePath {
+		return e.ctx.Value().Any(), nil
+	}
+
+	scope := e.ctx
+	if _, ok := scope.Schema().(*schema.ObjectSchema); !ok && scope.Parent() != nil {
+		scope = scope.Parent()
+	}
+
+	v, err := scope.Lookup(path)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	return v.Any(), nil
+}
+
 func registerField(r *Registry) {
 	r.Register("eqfield", compareFieldValidator(Equal))
 	r.Register("nefield", compareFieldValidator(NotEqual))
@@ -35,9 +86,23 @@ func registerField(r *Registry) {
 	r.Register("gtefield", compareFieldValidator(GreaterThanOrEqual))
 	r.Register("ltefield", compareFieldValidator(LessThanOrEqual))
 
+	// expr checks an expression parsed when the rule is built, such as expr=start < end && len(items) <= maxItems
+	r.Register("expr", func(ctx *schema.Context, e *expr.Expr) error {
+		ok, err := e.Match(exprEnv{ctx: ctx})
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return schema.ErrCheckFailed
+		}
+
+		return nil
+	})
+
 	r.Register("fieldcontains", func(ctx *schema.Context, fieldName string) error {
 		currentStr := ctx.Value().String()
-		otherValue, err := ctx.Parent().GetValue(fieldName)
+		otherValue, err := fieldValue(ctx, fieldName)
 		if err != nil {
 			return err
 		}
@@ -50,7 +115,7 @@ func registerField(r *Registry) {
 
 	r.Register("fieldexcludes", func(ctx *schema.Context, fieldName string) error {
 		currentStr := ctx.Value().String()
-		otherValue, err := ctx.Parent().GetValue(fieldName)
+		otherValue, err := fieldValue(ctx, fieldName)
 		if err != nil {
 			return err
 		}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/weilence/schema-validator/data"
+	"github.com/weilence/schema-validator/expr"
 	"github.com/weilence/schema-validator/schema"
 )
 
@@ -52,3 +53,56 @@ func Test_compareFieldValidator(t *testing.T) {
 		})
 	}
 }
+
+func Test_exprValidator(t *testing.T) {
+	r := NewRegistry()
+	registerField(r)
+
+	type item struct {
+		Price int
+	}
+
+	type order struct {
+		Min   int
+		Max   int
+		Items []item
+		Note  string
+	}
+
+	tests := []struct {
+		name    string
+		expr    string
+		object  bool
+		value   order
+		wantErr bool
+	}{
+		{name: "siblings success", expr: "Min < Max", value: order{Min: 1, Max: 2}},
+		{name: "siblings failure", expr: "Min < Max", value: order{Min: 2, Max: 1}, wantErr: true},
+		{name: "value success", expr: "$value <= Max", value: order{Min: 1, Max: 2}},
+		{name: "value failure", expr: "$value >= Max", value: order{Min: 1, Max: 2}, wantErr: true},
+		{name: "object success", expr: "len(Items) <= Max && len(Note) == 0", object: true, value: order{Max: 1, Items: []item{{}}}},
+		{name: "object failure", expr: "len(Items) <= Max", object: true, value: order{Max: 1, Items: []item{{}, {}}}, wantErr: true},
+		{name: "missing value", expr: "Note != ''", value: order{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := r.NewValidator("expr", tt.expr)
+			s := schema.NewObject().AddField("Max", schema.NewField())
+			if tt.object {
+				s.AddValidator(v)
+			} else {
+				s.AddField("Min", schema.NewField().AddValidator(v))
+			}
+
+			ctx := schema.NewContext(s, data.New(tt.value))
+			err := s.Validate(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantErr, ctx.Errors().HasErrorCode("expr"))
+		})
+	}
+
+	// Expressions are parsed when the validator is built
+	_, err := r.NewValidatorE("expr", "Min <")
+	assert.ErrorIs(t, err, ErrInvalidParams)
+	assert.ErrorIs(t, err, expr.ErrSyntax)
+}
@@ -0,0 +1,36 @@
+package rule
+
+import (
+	"slices"
+
+	"github.com/weilence/schema-validator/schema"
+)
+
+// grouped is a validator that only runs when one of its groups is active
+type grouped struct {
+	schema.Validator
+	groups []string
+}
+
+// Groups returns the groups the validator is limited to
+func (g grouped) Groups() []string {
+	return slices.Clone(g.groups)
+}
+
+func (g grouped) Validate(ctx *schema.Context) error {
+	if !ctx.InGroups(g.groups) {
+		return nil
+	}
+
+	return g.Validator.Validate(ctx)
+}
+
+// InGroups limits v to validation groups, keeping its name and params
+// Without groups v is returned as is and runs in every group
+func InGroups(v schema.Validator, groups ...string) schema.Validator {
+	if len(groups) == 0 {
+		return v
+	}
+
+	return grouped{Validator: v, groups: slices.Clone(groups)}
+}
@@ -2,10 +2,13 @@ package rule
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"slices"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/weilence/schema-validator/data"
 	"github.com/weilence/schema-validator/schema"
@@ -91,7 +94,7 @@ func registerOther(r *Registry) {
 
 	r.Register("len", func(ctx *schema.Context, expectedLen int) error {
 		str := ctx.Value().String()
-		if len(str) == expectedLen {
+		if utf8.RuneCountInString(str) == expectedLen {
 			return nil
 		}
 		return schema.ErrCheckFailed
@@ -113,9 +116,9 @@ func registerOther(r *Registry) {
 	r.Register("required", requiredFn)
 
 	r.Register("required_if", func(ctx *schema.Context, fieldName string, expectedValue any) error {
-		otherValue, err := ctx.Parent().GetValue(fieldName)
+		otherValue, err := fieldValue(ctx, fieldName)
 		if err != nil {
-			return fmt.Errorf("failed to get field '%s': %v", fieldName, err)
+			return err
 		}
 
 		ok, err := compareValue(Equal, otherValue, data.NewValue(expectedValue))
@@ -131,9 +134,9 @@ func registerOther(r *Registry) {
 	})
 
 	r.Register("required_unless", func(ctx *schema.Context, fieldName string, expectedValue any) error {
-		otherValue, err := ctx.Parent().GetValue(fieldName)
+		otherValue, err := fieldValue(ctx, fieldName)
 		if err != nil {
-			return fmt.Errorf("failed to get field '%s': %v", fieldName, err)
+			return err
 		}
 
 		ok, err := compareValue(NotEqual, otherValue, data.NewValue(expectedValue))
@@ -150,9 +153,9 @@ func registerOther(r *Registry) {
 
 	r.Register("required_with", func(ctx *schema.Context, fieldNames []string) error {
 		for _, fieldName := range fieldNames {
-			otherValue, err := ctx.Parent().GetValue(fieldName)
+			otherValue, err := fieldValue(ctx, fieldName)
 			if err != nil {
-				return fmt.Errorf("failed to get field '%s': %v", fieldName, err)
+				return err
 			}
 			if !otherValue.IsNilOrZero() {
 				return requiredFn(ctx)
@@ -164,9 +167,9 @@ func registerOther(r *Registry) {
 	r.Register("required_with_all", func(ctx *schema.Context, fieldNames []string) error {
 		allPresent := true
 		for _, fieldName := range fieldNames {
-			otherValue, err := ctx.Parent().GetValue(fieldName)
+			otherValue, err := fieldValue(ctx, fieldName)
 			if err != nil {
-				return fmt.Errorf("failed to get field '%s': %v", fieldName, err)
+				return err
 			}
 			if otherValue.IsNilOrZero() {
 				allPresent = false
@@ -181,9 +184,9 @@ func registerOther(r *Registry) {
 
 	r.Register("required_without", func(ctx *schema.Context, fieldNames []string) error {
 		for _, fieldName := range fieldNames {
-			otherValue, err := ctx.Parent().GetValue(fieldName)
+			otherValue, err := fieldValue(ctx, fieldName)
 			if err != nil {
-				return fmt.Errorf("failed to get field '%s': %v", fieldName, err)
+				return err
 			}
 			if otherValue.IsNilOrZero() {
 				return requiredFn(ctx)
@@ -195,9 +198,9 @@ func registerOther(r *Registry) {
 	r.Register("required_without_all", func(ctx *schema.Context, fieldNames []string) error {
 		allAbsent := true
 		for _, fieldName := range fieldNames {
-			otherValue, err := ctx.Parent().GetValue(fieldName)
+			otherValue, err := fieldValue(ctx, fieldName)
 			if err != nil {
-				return fmt.Errorf("failed to get field '%s': %v", fieldName, err)
+				return err
 			}
 			if !otherValue.IsNilOrZero() {
 				allAbsent = false
@@ -211,9 +214,9 @@ func registerOther(r *Registry) {
 	})
 
 	r.Register("excluded_if", func(ctx *schema.Context, fieldName string, expectedValue any) error {
-		otherValue, err := ctx.Parent().GetValue(fieldName)
+		otherValue, err := fieldValue(ctx, fieldName)
 		if err != nil {
-			return fmt.Errorf("failed to get field '%s': %v", fieldName, err)
+			return err
 		}
 
 		ok, err := compareValue(Equal, otherValue, data.NewValue(expectedValue))
@@ -229,9 +232,9 @@ func registerOther(r *Registry) {
 	})
 
 	r.Register("excluded_unless", func(ctx *schema.Context, fieldName string, expectedValue any) error {
-		otherValue, err := ctx.Parent().GetValue(fieldName)
+		otherValue, err := fieldValue(ctx, fieldName)
 		if err != nil {
-			return fmt.Errorf("failed to get field '%s': %v", fieldName, err)
+			return err
 		}
 
 		ok, err := compareValue(NotEqual, otherValue, data.NewValue(expectedValue))
@@ -248,9 +251,9 @@ func registerOther(r *Registry) {
 
 	r.Register("excluded_with", func(ctx *schema.Context, fieldNames []string) error {
 		for _, fieldName := range fieldNames {
-			otherValue, err := ctx.Parent().GetValue(fieldName)
+			otherValue, err := fieldValue(ctx, fieldName)
 			if err != nil {
-				return fmt.Errorf("failed to get field '%s': %v", fieldName, err)
+				return err
 			}
 			if !otherValue.IsNilOrZero() && !ctx.Value().IsNilOrZero() {
 				return schema.ErrCheckFailed
@@ -262,9 +265,9 @@ func registerOther(r *Registry) {
 	r.Register("excluded_with_all", func(ctx *schema.Context, fieldNames []string) error {
 		allPresent := true
 		for _, fieldName := range fieldNames {
-			otherValue, err := ctx.Parent().GetValue(fieldName)
+			otherValue, err := fieldValue(ctx, fieldName)
 			if err != nil {
-				return fmt.Errorf("failed to get field '%s': %v", fieldName, err)
+				return err
 			}
 			if otherValue.IsNilOrZero() {
 				allPresent = false
@@ -279,9 +282,9 @@ func registerOther(r *Registry) {
 
 	r.Register("excluded_without", func(ctx *schema.Context, fieldNames []string) error {
 		for _, fieldName := range fieldNames {
-			otherValue, err := ctx.Parent().GetValue(fieldName)
+			otherValue, err := fieldValue(ctx, fieldName)
 			if err != nil {
-				return fmt.Errorf("failed to get field '%s': %v", fieldName, err)
+				return err
 			}
 			if otherValue.IsNilOrZero() && !ctx.Value().IsNilOrZero() {
 				return schema.ErrCheckFailed
@@ -293,9 +296,9 @@ func registerOther(r *Registry) {
 	r.Register("excluded_without_all", func(ctx *schema.Context, fieldNames []string) error {
 		allAbsent := true
 		for _, fieldName := range fieldNames {
-			otherValue, err := ctx.Parent().GetValue(fieldName)
+			otherValue, err := fieldValue(ctx, fieldName)
 			if err != nil {
-				return fmt.Errorf("failed to get field '%s': %v", fieldName, err)
+				return err
 			}
 			if !otherValue.IsNilOrZero() {
 				allAbsent = false
@@ -323,4 +326,116 @@ func registerOther(r *Registry) {
 
 		return nil
 	})
+
+	// omitnil is like omitempty, but only skips absent and nil values, so zero values are still validated
+	r.Register("omitnil", func(ctx *schema.Context) error {
+		if isNil(ctx.Value()) {
+			ctx.SkipRest()
+		}
+
+		return nil
+	})
+
+	// present fails on absent and nil values, zero values such as "" or 0 pass
+	r.Register("present", func(ctx *schema.Context) error {
+		if isNil(ctx.Value()) {
+			return schema.ErrCheckFailed
+		}
+
+		return nil
+	})
+
+	// exists fails on fields missing from their object, fields holding nil pass
+	r.Register("exists", func(ctx *schema.Context) error {
+		if ctx.Absent() {
+			return schema.ErrCheckFailed
+		}
+
+		return nil
+	})
+
+	// omitabsent skips the remaining rules of fields missing from their object, so nil values are still validated
+	r.Register("omitabsent", func(ctx *schema.Context) error {
+		if ctx.Absent() {
+			ctx.SkipRest()
+		}
+
+		return nil
+	})
+
+	// allowed_keys fails on maps holding keys outside the list, other values pass
+	r.Register("allowed_keys", func(ctx *schema.Context, keys []string) error {
+		rv := reflect.ValueOf(ctx.Value().Any())
+		if rv.Kind() != reflect.Map {
+			return nil
+		}
+
+		for _, key := range rv.MapKeys() {
+			if !slices.Contains(keys, fmt.Sprint(key.Interface())) {
+				return schema.ErrCheckFailed
+			}
+		}
+
+		return nil
+	})
+
+	// type checks the JSON type of the value: string, number, integer, boolean, array, object or null
+	r.Register("type", func(ctx *schema.Context, types []string) error {
+		for _, t := range types {
+			ok, err := isJSONType(ctx.Value(), t)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				return nil
+			}
+		}
+
+		return schema.ErrCheckFailed
+	})
+}
+
+// isNil reports whether v is absent or a nil pointer, interface, map or slice
+func isNil(v *data.Value) bool {
+	rv := reflect.ValueOf(v.Raw())
+	if !rv.IsValid() {
+		return true
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+		return rv.IsNil()
+	}
+
+	return false
+}
+
+func isJSONType(v *data.Value, name string) (bool, error) {
+	if name == "null" {
+		return isNil(v), nil
+	}
+
+	rv := reflect.ValueOf(v.Any())
+	switch name {
+	case "string":
+		return rv.Kind() == reflect.String, nil
+	case "boolean":
+		return rv.Kind() == reflect.Bool, nil
+	case "number":
+		return rv.CanInt() || rv.CanUint() || rv.CanFloat(), nil
+	case "integer":
+		if rv.CanFloat() {
+			f := rv.Float()
+			return f == math.Trunc(f) && !math.IsInf(f, 0), nil
+		}
+
+		return rv.CanInt() || rv.CanUint(), nil
+	case "array":
+		return (rv.Kind() == reflect.Slice && !rv.IsNil()) || rv.Kind() == reflect.Array, nil
+	case "object":
+		return (rv.Kind() == reflect.Map && !rv.IsNil()) || rv.Kind() == reflect.Struct, nil
+	default:
+		return false, fmt.Errorf("unknown type %q", name)
+	}
 }
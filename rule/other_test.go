@@ -40,12 +40,17 @@ func TestOtherValidators(t *testing.T) {
 		// len
 		{"len valid", "len", "hello", []any{5}, false},
 		{"len invalid", "len", "hello", []any{3}, true},
+		{"len characters", "len", "héllo", []any{5}, false},
 		// max
 		{"max valid", "max", 5, []any{10}, false},
 		{"max invalid", "max", 15, []any{10}, true},
+		{"max absent", "max", nil, []any{10}, false},
+		{"max characters", "max", "日本語", []any{3}, false},
 		// min
 		{"min valid", "min", 10, []any{5}, false},
 		{"min invalid", "min", 3, []any{5}, true},
+		{"min absent", "min", nil, []any{5}, true},
+		{"min characters", "min", "日本", []any{3}, true},
 		// oneof
 		{"oneof valid", "oneof", "a", []any{[]string{"a", "b", "c"}}, false},
 		{"oneof invalid", "oneof", "d", []any{[]string{"a", "b", "c"}}, true},
@@ -54,6 +59,26 @@ func TestOtherValidators(t *testing.T) {
 		{"required valid 2", "required", ptr(0), nil, false},
 		{"required invalid", "required", "", nil, true},
 		{"required invalid 2", "required", 0, nil, true},
+		// omitnil skips nil but still validates zero values
+		{"omitnil nil", "omitnil", nil, nil, false},
+		// present
+		{"present valid", "present", "", nil, false},
+		{"present valid 2", "present", 0, nil, false},
+		{"present invalid", "present", nil, nil, true},
+		{"present invalid 2", "present", (*int)(nil), nil, true},
+		// type
+		{"type string", "type", "a", []any{[]string{"string"}}, false},
+		{"type integer", "type", 2.0, []any{[]string{"integer"}}, false},
+		{"type integer invalid", "type", 2.5, []any{[]string{"integer"}}, true},
+		{"type number", "type", 2.5, []any{[]string{"number"}}, false},
+		{"type object", "type", map[string]any{}, []any{[]string{"object"}}, false},
+		{"type array", "type", []any{1}, []any{[]string{"array"}}, false},
+		{"type nullable", "type", nil, []any{[]string{"string", "null"}}, false},
+		{"type invalid", "type", 1, []any{[]string{"string", "boolean"}}, true},
+		// allowed_keys
+		{"allowed_keys valid", "allowed_keys", map[string]any{"a": 1}, []any{[]string{"a", "b"}}, false},
+		{"allowed_keys invalid", "allowed_keys", map[string]any{"a": 1, "c": 2}, []any{[]string{"a", "b"}}, true},
+		{"allowed_keys not a map", "allowed_keys", "c", []any{[]string{"a"}}, false},
 		// unique (placeholder)
 		{"unique valid", "unique", "value", nil, false},
 		{"unique invalid", "unique", "value", nil, false}, // always pass for now
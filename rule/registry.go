@@ -1,9 +1,11 @@
 package rule
 
 import (
-	"errors"
+	"context"
+	"encoding"
 	"fmt"
 	"reflect"
+	"slices"
 
 	"github.com/weilence/schema-validator/schema"
 )
@@ -35,6 +37,105 @@ type validatorFactory struct {
 	name       string
 	paramTypes []reflect.Type
 	fn         func(ctx *schema.Context, params []any) error
+
+	// raw is set when fn takes the params slice as is, so any params are accepted
+	raw bool
+}
+
+func (vf validatorFactory) checkParams(params []any) error {
+	if vf.raw {
+		return nil
+	}
+
+	if len(params) != len(vf.paramTypes) {
+		return fmt.Errorf("%w: expected %d parameters, got %d", ErrInvalidParams, len(vf.paramTypes), len(params))
+	}
+
+	for i, param := range params {
+		paramType := vf.paramTypes[i]
+		if param == nil {
+			switch paramType.Kind() {
+			case reflect.Interface, reflect.Pointer, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+				continue
+			}
+			return fmt.Errorf("%w: parameter %d must be %s, got nil", ErrInvalidParams, i+1, paramType)
+		}
+
+		if !reflect.TypeOf(param).AssignableTo(paramType) {
+			return fmt.Errorf("%w: parameter %d must be %s, got %T", ErrInvalidParams, i+1, paramType, param)
+		}
+	}
+
+	return nil
+}
+
+// parseTextParams parses string params passed for param types implementing encoding.TextUnmarshaler
+func (vf validatorFactory) parseTextParams(params []any) ([]any, error) {
+	if vf.raw || len(params) != len(vf.paramTypes) {
+		return params, nil
+	}
+
+	var parsed []any
+	for i, param := range params {
+		text, ok := param.(string)
+		if !ok {
+			continue
+		}
+
+		v, ok, err := ParseTextParam(vf.paramTypes[i], text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: parameter %d: %w", ErrInvalidParams, i+1, err)
+		}
+
+		if ok {
+			if parsed == nil {
+				parsed = slices.Clone(params)
+			}
+			parsed[i] = v
+		}
+	}
+
+	if parsed == nil {
+		return params, nil
+	}
+
+	return parsed, nil
+}
+
+// ParseTextParam parses text into a param of paramType when paramType or its pointer
+// implements encoding.TextUnmarshaler, such as *expr.Expr; ok is false for other types
+func ParseTextParam(paramType reflect.Type, text string) (param any, ok bool, err error) {
+	if !isTextParam(paramType) {
+		return nil, false, nil
+	}
+
+	unmarshaler := reflect.TypeFor[encoding.TextUnmarshaler]()
+	switch {
+	case paramType.Kind() == reflect.Pointer && paramType.Implements(unmarshaler):
+		rv := reflect.New(paramType.Elem())
+		if err := rv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+			return nil, true, err
+		}
+		return rv.Interface(), true, nil
+	case paramType.Kind() != reflect.Interface && reflect.PointerTo(paramType).Implements(unmarshaler):
+		rv := reflect.New(paramType)
+		if err := rv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+			return nil, true, err
+		}
+		return rv.Elem().Interface(), true, nil
+	default:
+		return nil, false, nil
+	}
+}
+
+// isTextParam reports whether paramType or its pointer implements encoding.TextUnmarshaler
+func isTextParam(paramType reflect.Type) bool {
+	unmarshaler := reflect.TypeFor[encoding.TextUnmarshaler]()
+	if paramType.Kind() == reflect.Pointer {
+		return paramType.Implements(unmarshaler)
+	}
+
+	return paramType.Kind() != reflect.Interface && reflect.PointerTo(paramType).Implements(unmarshaler)
 }
 
 func (vf validatorFactory) Build(params []any) schema.Validator {
@@ -58,26 +159,38 @@ func NewRegistry() *Registry {
 }
 
 // Register registers a field validator factory
+// Factories may take a context.Context before the *schema.Context, receiving the context
+// passed to the validation, such as by Validator.ValidateContext
+// Errors wrapping schema.ErrCheckFailed are recorded as validation errors, others are
+// reported as internal errors by schema.Context.ReportError
 func (r *Registry) Register(code string, fn any) {
 	rv := reflect.ValueOf(fn)
 	rvType := rv.Type()
 	if rvType.Kind() != reflect.Func {
 		panic("validator factory must be a function")
 	}
-	if rvType.NumIn() < 1 || rvType.In(0) != reflect.TypeFor[*schema.Context]() {
-		panic("first parameter of validator factory must be *schema.Context")
+
+	// offset is the number of leading parameters not taken from the rule params
+	offset := 1
+	withContext := rvType.NumIn() > 0 && rvType.In(0) == reflect.TypeFor[context.Context]()
+	if withContext {
+		offset = 2
+	}
+	if rvType.NumIn() < offset || rvType.In(offset-1) != reflect.TypeFor[*schema.Context]() {
+		panic("first parameter of validator factory must be *schema.Context, optionally preceded by context.Context")
 	}
 	if rvType.NumOut() != 1 || rvType.Out(0) != reflect.TypeFor[error]() {
 		panic("validator factory must return a single error value")
 	}
 
 	rvParamTypes := make([]reflect.Type, 0)
-	for i := 1; i < rvType.NumIn(); i++ {
+	for i := offset; i < rvType.NumIn(); i++ {
 		rvParamTypes = append(rvParamTypes, rvType.In(i))
 	}
 
 	var newFn func(ctx *schema.Context, params []any) error
-	if typedFn, ok := fn.(func(*schema.Context, []any) error); ok {
+	typedFn, raw := fn.(func(*schema.Context, []any) error)
+	if raw {
 		newFn = typedFn
 	} else {
 		newFn = func(ctx *schema.Context, params []any) (err error) {
@@ -87,10 +200,13 @@ func (r *Registry) Register(code string, fn any) {
 				}
 			}()
 
-			rvParams := make([]reflect.Value, len(rvParamTypes)+1)
-			rvParams[0] = reflect.ValueOf(ctx)
+			rvParams := make([]reflect.Value, len(rvParamTypes)+offset)
+			if withContext {
+				rvParams[0] = reflect.ValueOf(ctx.Context())
+			}
+			rvParams[offset-1] = reflect.ValueOf(ctx)
 			for i, param := range params {
-				rvParams[i+1] = reflect.ValueOf(param)
+				rvParams[i+offset] = reflect.ValueOf(param)
 			}
 
 			outs := rv.Call(rvParams)
@@ -110,18 +226,12 @@ func (r *Registry) Register(code string, fn any) {
 	newFn2 := func(ctx *schema.Context, params []any) error {
 		err := newFn(ctx, params)
 		if err != nil {
-			newErr := schema.ValidationError{
+			return ctx.ReportError(schema.ValidationError{
 				Path:   ctx.Path(),
 				Code:   code,
 				Params: params,
 				Err:    err,
-			}
-
-			if errors.Is(err, schema.ErrCheckFailed) {
-				ctx.AddError(newErr)
-			} else {
-				return newErr
-			}
+			})
 		}
 
 		return nil
@@ -131,6 +241,7 @@ func (r *Registry) Register(code string, fn any) {
 		name:       code,
 		paramTypes: rvParamTypes,
 		fn:         newFn2,
+		raw:        raw,
 	}
 }
 
@@ -145,22 +256,69 @@ func (r *Registry) Alias(oldName, newName string) {
 
 // NewValidator gets a field validator by name
 // params is a slice of parameter strings
+// It panics if the validator is unknown or params don't match, see NewValidatorE
 func (r *Registry) NewValidator(name string, params ...any) schema.Validator {
+	v, err := r.NewValidatorE(name, params...)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return v
+}
+
+// NewValidatorE gets a field validator by name, returning an error if the validator
+// is unknown or params don't match its signature
+func (r *Registry) NewValidatorE(name string, params ...any) (schema.Validator, error) {
 	factory, ok := r.validators[name]
 	if !ok {
-		panic(fmt.Sprintf("validator '%s' not found in registry", name))
+		return nil, BuildError{Rule: name, Err: ErrNotFound}
+	}
+
+	params, err := factory.parseTextParams(params)
+	if err != nil {
+		return nil, BuildError{Rule: name, Err: err}
 	}
 
-	return factory.Build(params)
+	if err := factory.checkParams(params); err != nil {
+		return nil, BuildError{Rule: name, Err: err}
+	}
+
+	return factory.Build(params), nil
 }
 
+// GetValidatorParamTypes returns the parameter types of a validator
+// It panics if the validator is unknown, see GetValidatorParamTypesE
 func (r *Registry) GetValidatorParamTypes(name string) []reflect.Type {
+	paramTypes, err := r.GetValidatorParamTypesE(name)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return paramTypes
+}
+
+// GetValidatorParamTypesE returns the parameter types of a validator,
+// or an error if the validator is unknown
+func (r *Registry) GetValidatorParamTypesE(name string) ([]reflect.Type, error) {
 	factory, ok := r.validators[name]
 	if !ok {
-		panic(fmt.Sprintf("validator '%s' not found in registry", name))
+		return nil, BuildError{Rule: name, Err: ErrNotFound}
 	}
 
-	return factory.paramTypes
+	return factory.paramTypes, nil
+}
+
+// TakesTextParam reports whether a validator takes a single param parsed from text, such as
+// the *expr.Expr of expr, whose tag param is kept whole, see tag.Parser.ParseFunc
+func (r *Registry) TakesTextParam(name string) bool {
+	factory, ok := r.validators[name]
+	return ok && !factory.raw && len(factory.paramTypes) == 1 && isTextParam(factory.paramTypes[0])
+}
+
+// TakesRawParams reports whether a validator takes its params slice as is, such as rules
+// registered with RegisterAsync, so params written in tags are passed as strings
+func (r *Registry) TakesRawParams(name string) bool {
+	return r.validators[name].raw
 }
 
 // DefaultRegistry returns the default registry
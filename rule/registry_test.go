@@ -1,6 +1,8 @@
 package rule
 
 import (
+	"context"
+	"reflect"
 	"testing"
 
 	"github.com/weilence/schema-validator/data"
@@ -64,3 +66,32 @@ func TestThreeParameterValidator(t *testing.T) {
 		return nil
 	})
 }
+
+type tenantKey struct{}
+
+// Test validator factory taking the context of the validation
+func TestContextParameterValidator(t *testing.T) {
+	r := NewRegistry()
+	r.Register("tenant", func(c context.Context, ctx *schema.Context, tenant string) error {
+		if c.Value(tenantKey{}) != tenant {
+			return schema.ErrCheckFailed
+		}
+		return nil
+	})
+
+	if types := r.GetValidatorParamTypes("tenant"); !reflect.DeepEqual(types, []reflect.Type{reflect.TypeFor[string]()}) {
+		t.Fatalf("Expected a single string param, got %v", types)
+	}
+
+	s := schema.NewField().AddValidator(r.NewValidator("tenant", "acme"))
+	for tenant, valid := range map[string]bool{"acme": true, "other": false} {
+		ctx := schema.NewContext(s, data.NewValue(1))
+		ctx.SetContext(context.WithValue(context.Background(), tenantKey{}, tenant))
+		if err := s.Validate(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := len(ctx.Errors()) == 0; got != valid {
+			t.Errorf("tenant %s: expected valid=%v, got errors %v", tenant, valid, ctx.Errors())
+		}
+	}
+}
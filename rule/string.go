@@ -1,6 +1,7 @@
 package rule
 
 import (
+	"regexp"
 	"strings"
 	"unicode"
 
@@ -237,4 +238,34 @@ func registerString(r *Registry) {
 		return schema.ErrCheckFailed
 	})
 	// ------------------------ end of workaround ------------------------
+
+	// pattern matches the value against a regular expression, unanchored like JSON Schema's pattern
+	r.Register("pattern", func(ctx *schema.Context, re *Pattern) error {
+		if re.MatchString(ctx.Value().String()) {
+			return nil
+		}
+		return schema.ErrCheckFailed
+	})
+}
+
+// Pattern is a regular expression param, compiled when the rule is built so that invalid
+// expressions are reported as build errors
+type Pattern struct {
+	*regexp.Regexp
+}
+
+// MarshalText returns the source of the expression
+func (p *Pattern) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText compiles an expression, so patterns can be written in tags
+func (p *Pattern) UnmarshalText(text []byte) error {
+	re, err := regexp.Compile(string(text))
+	if err != nil {
+		return err
+	}
+
+	p.Regexp = re
+	return nil
 }
@@ -91,6 +91,10 @@ func TestStringValidators(t *testing.T) {
 		// uppercase
 		{"uppercase valid", "uppercase", "HELLO", nil, false},
 		{"uppercase invalid", "uppercase", "Hello", nil, true},
+		// pattern
+		{"pattern valid", "pattern", "ab-12", []any{`^[a-z]+-\d+$`}, false},
+		{"pattern unanchored", "pattern", "xx12yy", []any{`\d+`}, false},
+		{"pattern invalid", "pattern", "ab-x", []any{`^[a-z]+-\d+$`}, true},
 	}
 
 	for _, tt := range tests {
@@ -104,3 +108,11 @@ func TestStringValidators(t *testing.T) {
 		})
 	}
 }
+
+func TestPatternBuildError(t *testing.T) {
+	r := NewRegistry()
+	registerString(r)
+
+	_, err := r.NewValidatorE("pattern", "([")
+	assert.ErrorIs(t, err, ErrInvalidParams)
+}
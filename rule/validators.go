@@ -3,6 +3,8 @@ package rule
 import (
 	"cmp"
 	"fmt"
+	"reflect"
+	"unicode/utf8"
 
 	"github.com/spf13/cast"
 	"github.com/weilence/schema-validator/data"
@@ -72,6 +74,18 @@ func compareFn[T cmp.Ordered](t compareType, a, b T) bool {
 
 func compareValue(ct compareType, currentValue, otherValue *data.Value) (bool, error) {
 	switch v := currentValue.Raw().(type) {
+	case nil:
+		// Absent values, such as missing map keys, compare like zero values
+		if b, ok := otherValue.Raw().(string); ok {
+			return compareFn(ct, "", b), nil
+		}
+
+		b, err := cast.ToE[float64](otherValue.Raw())
+		if err != nil {
+			return false, err
+		}
+
+		return compareFn(ct, 0, b), nil
 	case int, int8, int16, int32, int64:
 		a, err := cast.ToE[int64](v)
 		if err != nil {
@@ -124,9 +138,10 @@ func compareValue(ct compareType, currentValue, otherValue *data.Value) (bool, e
 			return compareFn(ct, a, bStr), nil
 		}
 
-		return compareFn(ct, len(a), b), nil
+		// Lengths count characters, as JSON Schema's minLength and maxLength do
+		return compareFn(ct, utf8.RuneCountInString(a), b), nil
 	default:
-		if currentValue.IsSliceOrArray() {
+		if currentValue.IsSliceOrArray() || currentValue.Kind() == reflect.Map {
 			b := cast.ToInt(otherValue.Raw())
 			return compareFn(ct, currentValue.Len(), b), nil
 		}
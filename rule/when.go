@@ -0,0 +1,66 @@
+package rule
+
+import (
+	"fmt"
+
+	"github.com/weilence/schema-validator/schema"
+)
+
+// Keywords of conditional rules such as when=country,US|len=5|else|max=10
+const (
+	WhenName = "when"
+	ElseName = "else"
+)
+
+// when is a validator running one of two lists of validators depending on a condition
+type when struct {
+	condition schema.Condition
+	then      *schema.FieldSchema
+	els       *schema.FieldSchema
+}
+
+func (w when) Name() string {
+	return WhenName
+}
+
+// Params returns the condition
+func (w when) Params() []any {
+	return []any{w.condition}
+}
+
+func (w when) Validate(ctx *schema.Context) error {
+	// Conditions are evaluated against the object holding the value, so paths name its siblings
+	object := ctx.Parent()
+	if object == nil {
+		object = ctx
+	}
+
+	ok, err := w.condition.Match(object)
+	if err != nil {
+		return ctx.ReportError(schema.ValidationError{
+			Path: ctx.Path(),
+			Code: WhenName,
+			Err:  fmt.Errorf("error evaluating condition: %w", err),
+		})
+	}
+
+	if ok {
+		return w.then.Validate(ctx)
+	}
+
+	return w.els.Validate(ctx)
+}
+
+// When returns a validator running then when condition matches and els otherwise
+// The condition is evaluated with the context of the object holding the validated value
+func When(condition schema.Condition, then, els []schema.Validator) schema.Validator {
+	w := when{condition: condition, then: schema.NewField(), els: schema.NewField()}
+	for _, v := range then {
+		w.then.AddValidator(v)
+	}
+	for _, v := range els {
+		w.els.AddValidator(v)
+	}
+
+	return w
+}
@@ -2,6 +2,8 @@ package schema
 
 import (
 	"fmt"
+	"reflect"
+	"slices"
 
 	"github.com/weilence/schema-validator/data"
 )
@@ -23,8 +25,9 @@ func NewArray(element Schema) *ArraySchema {
 
 // Validate validates an array
 func (a *ArraySchema) Validate(ctx *Context) error {
+	reported := len(ctx.Errors())
 	for _, validator := range a.validators {
-		if ctx.skipRest {
+		if ctx.skipRest || ctx.skipValidators(reported) {
 			break
 		}
 
@@ -33,14 +36,32 @@ func (a *ArraySchema) Validate(ctx *Context) error {
 		}
 	}
 
+	if ctx.skipRest {
+		return nil
+	}
+
 	// Validate each element
 	accessor, ok := ctx.Accessor().(*data.ArrayAccessor)
 	if !ok {
+		// A missing value or one of the wrong shape is fine as long as an array-level rule reported it
+		if v, isValue := ctx.Accessor().(*data.Value); isValue && v.Kind() == reflect.Invalid || len(ctx.Errors()) > reported {
+			return nil
+		}
+
 		return fmt.Errorf("expected ArrayAccessor, got %T", ctx.Accessor())
 	}
 
 	return accessor.Iterate(func(idx int, childAccessor data.Accessor) error {
-		elemCtx := ctx.WithChild(fmt.Sprintf("[%d]", idx), a.element, childAccessor)
+		segment := fmt.Sprintf("[%d]", idx)
+		if ctx.skips(segment) || ctx.halted() {
+			return nil
+		}
+
+		if err := ctx.checkCanceled(segment); err != nil {
+			return err
+		}
+
+		elemCtx := ctx.WithChild(segment, a.element, childAccessor)
 		return a.element.Validate(elemCtx)
 	})
 }
@@ -64,3 +85,8 @@ func (a *ArraySchema) RemoveValidator(name string) Schema {
 	a.validators = newValidators
 	return a
 }
+
+// Validators returns the validators in the order they run
+func (a *ArraySchema) Validators() []Validator {
+	return slices.Clone(a.validators)
+}
@@ -0,0 +1,225 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// CompositeMode is how a CompositeSchema combines its branches
+type CompositeMode int
+
+const (
+	// AllOf requires every branch to pass, branch errors are reported as is
+	AllOf CompositeMode = iota
+	// AnyOf requires at least one branch to pass
+	AnyOf
+	// OneOf requires exactly one branch to pass
+	OneOf
+	// Not requires its single branch to fail
+	Not
+)
+
+// String returns the error code used for failures of the mode
+func (m CompositeMode) String() string {
+	switch m {
+	case AllOf:
+		return "allOf"
+	case AnyOf:
+		return "anyOf"
+	case OneOf:
+		return "oneOf"
+	case Not:
+		return "not"
+	default:
+		return fmt.Sprintf("CompositeMode(%d)", int(m))
+	}
+}
+
+// BranchErrors holds the errors of each failed branch of a composite schema, in branch order
+// It is the Err of the single ValidationError reported for anyOf and oneOf failures
+type BranchErrors []ValidationErrors
+
+func (b BranchErrors) Unwrap() []error {
+	errs := make([]error, 0, len(b))
+	for _, branch := range b {
+		errs = append(errs, branch)
+	}
+
+	return errs
+}
+
+func (b BranchErrors) Error() string {
+	return errors.Join(b.Unwrap()...).Error()
+}
+
+// CompositeSchema validates the same value against several schemas
+type CompositeSchema struct {
+	mode     CompositeMode
+	branches []Schema
+
+	validators []Validator
+}
+
+// NewComposite creates a composite schema
+func NewComposite(mode CompositeMode, branches ...Schema) *CompositeSchema {
+	return &CompositeSchema{
+		mode:       mode,
+		branches:   branches,
+		validators: make([]Validator, 0),
+	}
+}
+
+// NewAllOf creates a composite schema that requires every branch to pass
+func NewAllOf(branches ...Schema) *CompositeSchema {
+	return NewComposite(AllOf, branches...)
+}
+
+// NewAnyOf creates a composite schema that requires at least one branch to pass
+func NewAnyOf(branches ...Schema) *CompositeSchema {
+	return NewComposite(AnyOf, branches...)
+}
+
+// NewOneOf creates a composite schema that requires exactly one branch to pass
+func NewOneOf(branches ...Schema) *CompositeSchema {
+	return NewComposite(OneOf, branches...)
+}
+
+// NewNot creates a composite schema that requires the branch to fail
+func NewNot(branch Schema) *CompositeSchema {
+	return NewComposite(Not, branch)
+}
+
+// Mode returns how the branches are combined
+func (c *CompositeSchema) Mode() CompositeMode {
+	return c.mode
+}
+
+// Branches returns the branch schemas in order
+func (c *CompositeSchema) Branches() []Schema {
+	return slices.Clone(c.branches)
+}
+
+// Validate runs the composite's own validators, then the branches
+// Branches see the same data and path; except for allOf, each branch collects its errors
+// separately and a failure is reported as one error with the mode as code
+// Internal errors collected in branches, see Context.SetCollectInternal, are reported as is
+// and no failure is reported when they leave the result undecided
+func (c *CompositeSchema) Validate(ctx *Context) error {
+	reported := len(ctx.Errors())
+	for _, validator := range c.validators {
+		if ctx.skipRest {
+			return nil
+		}
+
+		if ctx.skipValidators(reported) {
+			break
+		}
+
+		if err := validator.Validate(ctx); err != nil {
+			return err
+		}
+	}
+
+	if ctx.skipRest {
+		return nil
+	}
+
+	if c.mode == AllOf {
+		for _, branch := range c.branches {
+			if err := branch.Validate(ctx.branch(branch, nil)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	var failed BranchErrors
+	passed, undecided := 0, 0
+	for _, branch := range c.branches {
+		errs := ValidationErrors{}
+		branchCtx := ctx.branch(branch, &errs)
+		if err := branch.Validate(branchCtx); err != nil {
+			return err
+		}
+
+		// Deferred checks decide the branch result, so they can't wait for the end of traversal
+		if err := branchCtx.ResolveDeferred(); err != nil {
+			return err
+		}
+
+		// Internal errors are failures of the rules rather than of the value, so they are
+		// reported as is, a branch without other errors having no result
+		var input ValidationErrors
+		internal := false
+		for _, err := range errs {
+			if err.Category == CategoryInternal {
+				ctx.AddError(err)
+				internal = true
+			} else {
+				input = append(input, err)
+			}
+		}
+
+		switch {
+		case len(input) > 0:
+			failed = append(failed, input)
+		case internal:
+			undecided++
+		default:
+			passed++
+		}
+	}
+
+	switch c.mode {
+	case AnyOf:
+		if passed > 0 || undecided > 0 {
+			return nil
+		}
+	case OneOf:
+		if passed == 1 || (passed == 0 && undecided > 0) {
+			return nil
+		}
+	case Not:
+		if passed == 0 {
+			return nil
+		}
+	}
+
+	err := ValidationError{
+		Path: ctx.Path(),
+		Code: c.mode.String(),
+		Err:  ErrCheckFailed,
+	}
+	if len(failed) > 0 {
+		err.Err = failed
+	}
+	if c.mode == OneOf {
+		err.Params = []any{passed}
+	}
+
+	ctx.AddError(err)
+	return nil
+}
+
+func (c *CompositeSchema) AddValidator(v Validator) Schema {
+	c.validators = append(c.validators, v)
+	return c
+}
+
+func (c *CompositeSchema) RemoveValidator(name string) Schema {
+	newValidators := make([]Validator, 0)
+	for _, v := range c.validators {
+		if v.Name() != name {
+			newValidators = append(newValidators, v)
+		}
+	}
+	c.validators = newValidators
+	return c
+}
+
+// Validators returns the composite's own validators in the order they run
+func (c *CompositeSchema) Validators() []Validator {
+	return slices.Clone(c.validators)
+}
@@ -0,0 +1,188 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// RootPathPrefix starts condition paths resolved from the root of the validated data
+const RootPathPrefix = "$root."
+
+// ParentPathPrefix moves condition paths up one level of the validated data
+const ParentPathPrefix = "../"
+
+// ConditionCode is the code of errors of conditions that couldn't be evaluated
+const ConditionCode = "when"
+
+// Condition is a predicate over the data of the object a conditional is attached to
+type Condition interface {
+	Match(ctx *Context) (bool, error)
+}
+
+// ConditionFunc is a custom condition, called with the context of the object
+type ConditionFunc func(ctx *Context) (bool, error)
+
+func (f ConditionFunc) Match(ctx *Context) (bool, error) {
+	return f(ctx)
+}
+
+// EqCondition matches when the value at Path equals Value
+type EqCondition struct {
+	Path  string
+	Value any
+}
+
+// Eq returns a condition matching when the value at path equals value
+// Values are compared by their formatted value, so "1" equals 1 and "true" equals true
+func Eq(path string, value any) EqCondition {
+	return EqCondition{Path: path, Value: value}
+}
+
+func (c EqCondition) Match(ctx *Context) (bool, error) {
+	v, err := ctx.Lookup(c.Path)
+	if err != nil || v == nil {
+		return false, err
+	}
+
+	return equalValues(v.Any(), c.Value), nil
+}
+
+// InCondition matches when the value at Path equals one of Values
+type InCondition struct {
+	Path   string
+	Values []any
+}
+
+// In returns a condition matching when the value at path equals one of values, compared as by Eq
+func In(path string, values ...any) InCondition {
+	return InCondition{Path: path, Values: values}
+}
+
+func (c InCondition) Match(ctx *Context) (bool, error) {
+	v, err := ctx.Lookup(c.Path)
+	if err != nil || v == nil {
+		return false, err
+	}
+
+	return slices.ContainsFunc(c.Values, func(value any) bool { return equalValues(v.Any(), value) }), nil
+}
+
+// PresentCondition matches when the value at Path is present and not nil
+type PresentCondition struct {
+	Path string
+}
+
+// Present returns a condition matching when the value at path is present and not nil
+func Present(path string) PresentCondition {
+	return PresentCondition{Path: path}
+}
+
+func (c PresentCondition) Match(ctx *Context) (bool, error) {
+	v, err := ctx.Lookup(c.Path)
+	if err != nil || v == nil {
+		return false, err
+	}
+
+	rv := reflect.ValueOf(v.Raw())
+	if !rv.IsValid() {
+		return false, nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+		return !rv.IsNil(), nil
+	}
+
+	return true, nil
+}
+
+func equalValues(actual, expected any) bool {
+	if actual == nil || expected == nil {
+		return actual == nil && expected == nil
+	}
+
+	for rv := reflect.ValueOf(actual); rv.Kind() == reflect.Pointer; rv = rv.Elem() {
+		if rv.IsNil() {
+			return expected == nil
+		}
+		actual = rv.Elem().Interface()
+	}
+
+	return fmt.Sprint(actual) == fmt.Sprint(expected)
+}
+
+// Conditional validates an object against Then when its condition matches, against Else otherwise
+// The branch schemas validate the same object as the schema holding the conditional, so an
+// object branch adds rules to fields for a single run without changing the compiled schema
+type Conditional struct {
+	condition Condition
+	then      Schema
+	els       Schema
+}
+
+// NewConditional creates a conditional without branches
+func NewConditional(condition Condition) *Conditional {
+	return &Conditional{condition: condition}
+}
+
+// Condition returns the condition selecting the branch
+func (c *Conditional) Condition() Condition {
+	return c.condition
+}
+
+// Then sets the schema validated when the condition matches
+func (c *Conditional) Then(s Schema) *Conditional {
+	c.then = s
+	return c
+}
+
+// Else sets the schema validated when the condition doesn't match
+func (c *Conditional) Else(s Schema) *Conditional {
+	c.els = s
+	return c
+}
+
+// ThenSchema returns the schema validated when the condition matches, nil if not set
+func (c *Conditional) ThenSchema() Schema {
+	return c.then
+}
+
+// ElseSchema returns the schema validated when the condition doesn't match, nil if not set
+func (c *Conditional) ElseSchema() Schema {
+	return c.els
+}
+
+// validate validates the object of ctx against the selected branch
+// fieldName resolves the data names of the fields of object branches
+func (c *Conditional) validate(ctx *Context, fieldName func(string) string) error {
+	ok, err := c.condition.Match(ctx)
+	if err != nil {
+		return ctx.ReportError(ValidationError{
+			Path: ctx.Path(),
+			Code: ConditionCode,
+			Err:  fmt.Errorf("error evaluating condition: %w", err),
+		})
+	}
+
+	s := c.els
+	if ok {
+		s = c.then
+	}
+
+	if s == nil {
+		return nil
+	}
+
+	if obj, ok := s.(*ObjectSchema); ok {
+		return obj.validateObject(ctx.branch(obj, nil), func(name string) string {
+			if mapped, ok := obj.fieldNameMap[name]; ok {
+				return mapped
+			}
+
+			return fieldName(name)
+		})
+	}
+
+	return s.Validate(ctx.branch(s, nil))
+}
@@ -1,16 +1,35 @@
 package schema
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/weilence/schema-validator/data"
 )
 
+// ErrCanceled 表示验证的 context 在遍历对象、数组和 map 时被取消或超时，返回的错误同时包装了 context 的错误
+var ErrCanceled = errors.New("validation canceled")
+
 // Context 封装验证的所有上下文信息
 type Context struct {
 	schema   Schema
 	accessor data.Accessor
 	skipRest bool
+	overlay  *Overlay
+
+	// 部分验证的路径过滤，nil 表示验证全部
+	filter *pathFilter
+
+	// 当前启用的验证组，nil 表示只启用 DefaultGroup
+	groups map[string]bool
+
+	// 调用方传入的 context，用于取消验证和传递请求范围的值，nil 表示 context.Background()
+	ctx context.Context
 
 	// 上下文信息
 	parent *Context
@@ -18,6 +37,18 @@ type Context struct {
 
 	// 收集的错误
 	errs *ValidationErrors
+
+	// 延迟到遍历结束后批量执行的检查，在整个验证中共享
+	deferred *[]deferredCheck
+
+	// 错误数量限制，nil 表示不限制
+	limits *errorLimits
+
+	// 是否将规则的内部错误记录为 CategoryInternal 的错误并继续验证，而不是中止验证
+	collectInternal bool
+
+	// 当前值是否在所属对象中缺失
+	absent bool
 }
 
 type contextPath []string
@@ -48,11 +79,127 @@ func NewContext(schema Schema, accessor data.Accessor) *Context {
 		schema:   schema,
 		accessor: accessor,
 		errs:     &ValidationErrors{},
+		deferred: &[]deferredCheck{},
 	}
 
 	return ctx
 }
 
+// Include 只验证给定路径上的字段及其下的全部内容，路径写法与验证错误相同，如 address.city 或 items[*].sku
+// 路径上的上级 schema 自身的验证器不会运行，跨字段规则仍可读取未包含的字段
+// 路径不在当前 schema 中时返回 ErrUnknownPath
+func (c *Context) Include(paths ...string) error {
+	filter, err := newPathFilter(true, c.schema, paths)
+	if err != nil {
+		return err
+	}
+
+	c.filter = filter
+	return nil
+}
+
+// Exclude 跳过给定路径上的字段及其下的全部内容，其余字段照常验证，路径不在当前 schema 中时返回 ErrUnknownPath
+func (c *Context) Exclude(paths ...string) error {
+	filter, err := newPathFilter(false, c.schema, paths)
+	if err != nil {
+		return err
+	}
+
+	c.filter = filter
+	return nil
+}
+
+// skips 返回当前 context 的子字段是否被部分验证跳过，field 为字段名或 [n]、[key] 形式的下标
+func (c *Context) skips(field string) bool {
+	return c.filter.skips(field)
+}
+
+// skipSelf 返回是否跳过当前 schema 自身的验证器，即只验证部分子字段时
+func (c *Context) skipSelf() bool {
+	return c.filter != nil && c.filter.include
+}
+
+// SetContext 设置本次验证的 context，其取消或超时会以 ErrCanceled 中止验证
+func (c *Context) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// Context 返回本次验证的 context，规则可从中读取租户、用户角色、语言等请求范围的值
+func (c *Context) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+
+	return c.ctx
+}
+
+// checkCanceled 在进入子字段 field 前检查 context，已取消或超时时返回包装了 ErrCanceled 和 context 错误的错误
+func (c *Context) checkCanceled(field string) error {
+	if c.ctx == nil {
+		return nil
+	}
+
+	if err := c.ctx.Err(); err != nil {
+		return fmt.Errorf("%w at %s: %w", ErrCanceled, newContextPath(c.path, field), err)
+	}
+
+	return nil
+}
+
+// SetGroups 设置启用的验证组，不设置时只启用 DefaultGroup
+// 未指定组的规则在所有组中运行，指定了组的规则只在其中一个组启用时运行
+func (c *Context) SetGroups(groups ...string) {
+	if len(groups) == 0 {
+		c.groups = nil
+		return
+	}
+
+	c.groups = make(map[string]bool, len(groups))
+	for _, group := range groups {
+		c.groups[group] = true
+	}
+}
+
+// InGroups 返回给定的组中是否有启用的组
+func (c *Context) InGroups(groups []string) bool {
+	for _, group := range groups {
+		if c.groups == nil && group == DefaultGroup || c.groups[group] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inheritGroups 按 parents 中组的继承关系启用被继承的组，只影响当前 context 及其子 context
+func (c *Context) inheritGroups(parents map[string][]string) {
+	if len(parents) == 0 {
+		return
+	}
+
+	active := c.groups
+	if active == nil {
+		active = map[string]bool{DefaultGroup: true}
+	}
+
+	expanded := maps.Clone(active)
+	queue := slices.Collect(maps.Keys(active))
+	for len(queue) > 0 {
+		group := queue[0]
+		queue = queue[1:]
+		for _, parent := range parents[group] {
+			if !expanded[parent] {
+				expanded[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+
+	if len(expanded) > len(active) {
+		c.groups = expanded
+	}
+}
+
 // WithChild 创建子 context（用于字段/元素验证）
 func (c *Context) WithChild(field string, childSchema Schema, childAccessor data.Accessor) *Context {
 	newPath := newContextPath(c.path, field)
@@ -61,9 +208,45 @@ func (c *Context) WithChild(field string, childSchema Schema, childAccessor data
 		schema:   childSchema,
 		accessor: childAccessor,
 
-		parent: c,
-		path:   newPath,
-		errs:   c.errs,
+		filter:          c.filter.descend(field),
+		groups:          c.groups,
+		ctx:             c.ctx,
+		collectInternal: c.collectInternal,
+
+		parent:   c,
+		path:     newPath,
+		errs:     c.errs,
+		deferred: c.deferred,
+		limits:   c.limits,
+	}
+}
+
+// branch 创建共享数据和路径的分支 context，用于组合 schema 的各个分支
+// errs 为 nil 时分支错误和延迟的检查直接记录到当前 context；否则分支单独收集二者，
+// 调用方需在判断分支结果前调用分支的 ResolveDeferred
+func (c *Context) branch(s Schema, errs *ValidationErrors) *Context {
+	deferred := c.deferred
+	if errs == nil {
+		errs = c.errs
+	} else {
+		deferred = &[]deferredCheck{}
+	}
+
+	return &Context{
+		schema:   s,
+		accessor: c.accessor,
+		filter:   c.filter,
+		groups:   c.groups,
+		ctx:      c.ctx,
+
+		collectInternal: c.collectInternal,
+		absent:          c.absent,
+
+		parent:   c.parent,
+		path:     c.path,
+		errs:     errs,
+		deferred: deferred,
+		limits:   c.limits,
 	}
 }
 
@@ -72,6 +255,21 @@ func (c *Context) Schema() Schema {
 	return c.schema
 }
 
+// Overlay 返回当前对象 schema 的写时复制视图，仅在本次验证中生效
+// 当前 schema 不是 ObjectSchema 时返回 nil
+func (c *Context) Overlay() *Overlay {
+	if c.overlay == nil {
+		base, ok := c.schema.(*ObjectSchema)
+		if !ok {
+			return nil
+		}
+
+		c.overlay = newOverlay(base)
+	}
+
+	return c.overlay
+}
+
 // Accessor 返回当前 accessor
 func (c *Context) Accessor() data.Accessor {
 	return c.accessor
@@ -95,15 +293,156 @@ func (c *Context) GetValue(path string) (*data.Value, error) {
 	return c.accessor.GetValue(path)
 }
 
+// Lookup 返回相对于当前数据的路径上的值，路径语法见 data.ParsePath
+// 以 RootPathPrefix 开头的路径从根数据解析，每个 ParentPathPrefix 向上一级数据（数组和 map 也算一级）
+// 每一级的字段名按对应 ObjectSchema 的字段映射解析，缺失的键、越界的下标和 nil 值返回 nil 且不返回错误
+func (c *Context) Lookup(path string) (*data.Value, error) {
+	target := c
+	if rest, ok := strings.CutPrefix(path, RootPathPrefix); ok {
+		target, path = c.Root(), rest
+	} else {
+		for strings.HasPrefix(path, ParentPathPrefix) {
+			if target.parent == nil {
+				return nil, fmt.Errorf("path %s goes above the root", path)
+			}
+			target, path = target.parent, path[len(ParentPathPrefix):]
+		}
+	}
+
+	segments, err := data.ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mapFieldNames(target.schema, segments)
+	acc, err := data.GetPath(target.accessor, segments)
+	if errors.Is(err, data.ErrKeyNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return acc.GetValue("")
+}
+
+// mapFieldNames 将路径上各级的字段名替换为 s 中对应 ObjectSchema 映射的数据字段名
+func mapFieldNames(s Schema, segments []data.PathSegment) {
+	for i, seg := range segments {
+		for {
+			ref, ok := s.(*RefSchema)
+			if !ok {
+				break
+			}
+			s = ref.Target()
+		}
+
+		switch typed := s.(type) {
+		case *ObjectSchema:
+			if !seg.IsIndex {
+				segments[i].Name = typed.FieldName(seg.Name)
+				s = typed.Field(seg.Name)
+				continue
+			}
+		case *ArraySchema:
+			s = typed.Element()
+			continue
+		case *MapSchema:
+			s = typed.Value()
+			continue
+		}
+		s = nil
+	}
+}
+
+// Absent 返回当前值是否在所属对象中缺失，用于区分缺失的字段和值为 null 的字段
+func (c *Context) Absent() bool {
+	return c.absent
+}
+
 func (c *Context) Parent() *Context {
 	return c.parent
 }
 
+// Root 返回根 context
+func (c *Context) Root() *Context {
+	root := c
+	for root.parent != nil {
+		root = root.parent
+	}
+
+	return root
+}
+
 func (c *Context) SkipRest() {
 	c.skipRest = true
 }
 
+// Defer 将 value 的检查延迟到遍历结束后，与同一 batch 的其他检查一起由 ResolveDeferred 批量执行
+// anyOf、oneOf 和 not 分支中延迟的检查在判断分支结果前执行
+// batch 的动态类型必须可比较，否则 panic
+func (c *Context) Defer(batch Batch, value any) {
+	if !reflect.ValueOf(batch).Comparable() {
+		panic(fmt.Sprintf("batch of type %T is not comparable", batch))
+	}
+
+	*c.deferred = append(*c.deferred, deferredCheck{batch: batch, path: c.path, value: value})
+}
+
+// SetErrorLimits 设置本次验证的错误数量限制
+func (c *Context) SetErrorLimits(limits ErrorLimits) {
+	c.limits = &errorLimits{ErrorLimits: limits, errs: c.errs}
+}
+
+// halted 返回验证是否已因 FailFast 或 MaxErrors 中止
+func (c *Context) halted() bool {
+	return c.limits != nil && c.limits.halted
+}
+
+// skipValidators 返回是否跳过自身剩余的验证器：部分验证跳过自身、验证已中止，
+// 或 Bail 模式下自身的验证器已报告错误，reported 为运行自身验证器前的错误数
+func (c *Context) skipValidators(reported int) bool {
+	if c.skipSelf() || c.halted() {
+		return true
+	}
+
+	return c.limits != nil && c.limits.Bail && len(c.Errors()) > reported
+}
+
+// SetCollectInternal 设置是否将规则的内部错误记录为 CategoryInternal 的错误并继续验证
+func (c *Context) SetCollectInternal(collect bool) {
+	c.collectInternal = collect
+}
+
+// ReportError 记录规则在 err.Path 上返回的错误 err.Err
+// 包装 ErrCheckFailed 的错误记录为输入错误；其他错误包装为 InternalError，
+// 设置 SetCollectInternal 时记录为 CategoryInternal 的错误，否则返回以中止验证
+func (c *Context) ReportError(err ValidationError) error {
+	if errors.Is(err.Err, ErrCheckFailed) {
+		c.AddError(err)
+		return nil
+	}
+
+	var internal InternalError
+	if !errors.As(err.Err, &internal) {
+		err.Err = InternalError{Err: err.Err}
+	}
+	err.Category = CategoryInternal
+
+	if !c.collectInternal {
+		return err
+	}
+
+	c.AddError(err)
+	return nil
+}
+
+// AddError 记录错误，组合 schema 分支单独收集的错误不受数量限制
 func (c *Context) AddError(err ValidationError) {
+	if c.limits != nil && c.errs == c.limits.errs {
+		c.limits.add(err)
+		return
+	}
+
 	c.errs.AddError(err)
 }
 
@@ -0,0 +1,112 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Batch resolves the checks deferred to it with Context.Defer in a single call once traversal
+// is complete, so that checks needing I/O don't take a round trip for every element
+// Batches are compared with ==, checks deferred to equal batches being resolved together,
+// so their dynamic types must be comparable, such as pointers
+type Batch interface {
+	// Name and Params are those of the rule, reported in validation errors
+	Name() string
+	Params() []any
+
+	// Resolve reports whether each of values passes the check, in order
+	Resolve(ctx context.Context, values []any) ([]bool, error)
+}
+
+// deferredCheck is a check of value deferred at path
+type deferredCheck struct {
+	batch Batch
+	path  contextPath
+	value any
+}
+
+// ResolveDeferred resolves the checks deferred during traversal, batch by batch in the order
+// batches were first used, recording failed checks as errors at the paths they were deferred at
+// Equal values deferred to a batch are resolved once. An error of a batch is reported as an
+// InternalError at the path of its first check, see Context.ReportError, unless the context of
+// the validation is done, which stops validation with an error wrapping ErrCanceled
+func (c *Context) ResolveDeferred() error {
+	if c.deferred == nil || len(*c.deferred) == 0 {
+		return nil
+	}
+
+	checks := *c.deferred
+	*c.deferred = nil
+
+	var order []Batch
+	batches := make(map[Batch][]deferredCheck)
+	for _, check := range checks {
+		if _, ok := batches[check.batch]; !ok {
+			order = append(order, check.batch)
+		}
+		batches[check.batch] = append(batches[check.batch], check)
+	}
+
+	for _, batch := range order {
+		if c.halted() {
+			break
+		}
+
+		if err := c.resolveBatch(batch, batches[batch]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Context) resolveBatch(batch Batch, checks []deferredCheck) error {
+	// slots maps each check to its value among the distinct values passed to the batch
+	var values []any
+	slots := make([]int, len(checks))
+	seen := make(map[any]int)
+	for i, check := range checks {
+		comparable := check.value == nil || reflect.ValueOf(check.value).Comparable()
+		if comparable {
+			if slot, ok := seen[check.value]; ok {
+				slots[i] = slot
+				continue
+			}
+			seen[check.value] = len(values)
+		}
+
+		slots[i] = len(values)
+		values = append(values, check.value)
+	}
+
+	results, err := batch.Resolve(c.Context(), values)
+	if ctxErr := c.Context().Err(); err != nil && ctxErr != nil {
+		// Only failures of the batch itself are internal errors, the validation being canceled isn't
+		return fmt.Errorf("%w at %s: %w", ErrCanceled, checks[0].path, ctxErr)
+	}
+	if err == nil && len(results) != len(values) {
+		err = fmt.Errorf("expected %d results, got %d", len(values), len(results))
+	}
+	if err != nil {
+		return c.ReportError(ValidationError{
+			Path:   checks[0].path.String(),
+			Code:   batch.Name(),
+			Params: batch.Params(),
+			Err:    err,
+		})
+	}
+
+	for i, check := range checks {
+		if !results[slots[i]] {
+			c.AddError(ValidationError{
+				Path:   check.path.String(),
+				Code:   batch.Name(),
+				Params: batch.Params(),
+				Err:    ErrCheckFailed,
+			})
+		}
+	}
+
+	return nil
+}
@@ -7,6 +7,32 @@ import (
 
 var ErrCheckFailed = fmt.Errorf("validation check failed")
 
+// ErrorCategory tells errors of the input apart from errors of the schema and its rules
+type ErrorCategory int
+
+const (
+	// CategoryInput is for values failing a check
+	CategoryInput ErrorCategory = iota
+
+	// CategoryInternal is for rules that couldn't check a value, see InternalError
+	CategoryInternal
+)
+
+// InternalError wraps an error returned by a rule other than a failed check, such as a
+// comparison of unsupported types, pointing to a bug in the schema rather than bad input
+type InternalError struct {
+	Err error
+}
+
+func (e InternalError) Unwrap() error {
+	return e.Err
+}
+
+// Error implements the error interface
+func (e InternalError) Error() string {
+	return "internal error: " + e.Err.Error()
+}
+
 // ValidationError represents a single validation failure with field path and error code
 type ValidationError struct {
 	Path string
@@ -17,6 +43,9 @@ type ValidationError struct {
 	// Params contains error parameters (positional)
 	Params []any
 
+	// Category is CategoryInternal for errors wrapping an InternalError
+	Category ErrorCategory
+
 	Err error
 }
 
@@ -26,10 +55,18 @@ func (e ValidationError) Unwrap() error {
 
 // Error implements the error interface
 func (e ValidationError) Error() string {
+	// Errors of standalone values, such as those of validator.Var, have no path
+	msg := e.Code
+	if e.Path != "" {
+		msg = fmt.Sprintf("%s: %s", e.Path, e.Code)
+	}
 	if len(e.Params) > 0 {
-		return fmt.Sprintf("%s: %s %v", e.Path, e.Code, e.Params)
+		msg = fmt.Sprintf("%s %v", msg, e.Params)
 	}
-	return fmt.Sprintf("%s: %s", e.Path, e.Code)
+	if e.Category == CategoryInternal && e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
 }
 
 // ValidationErrors holds all validation errors
@@ -82,6 +119,17 @@ func (r ValidationErrors) HasErrorCode(code string) bool {
 	return false
 }
 
+// HasInternalError reports whether any error is of CategoryInternal
+func (r ValidationErrors) HasInternalError() bool {
+	for _, err := range r {
+		if err.Category == CategoryInternal {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (r ValidationErrors) Translate(translator func(err ValidationError) string) map[string][]string {
 	res := make(map[string][]string)
 	for _, err := range r {
@@ -1,5 +1,7 @@
 package schema
 
+import "slices"
+
 // FieldSchema validates primitive/scalar values
 type FieldSchema struct {
 	validators []Validator
@@ -15,8 +17,9 @@ func NewField() *FieldSchema {
 // Validate validates a field value
 func (f *FieldSchema) Validate(ctx *Context) error {
 	// Run all validators
+	reported := len(ctx.Errors())
 	for _, validator := range f.validators {
-		if ctx.skipRest {
+		if ctx.skipRest || ctx.skipValidators(reported) {
 			break
 		}
 
@@ -43,3 +46,8 @@ func (f *FieldSchema) RemoveValidator(name string) Schema {
 	f.validators = newValidators
 	return f
 }
+
+// Validators returns the validators in the order they run
+func (f *FieldSchema) Validators() []Validator {
+	return slices.Clone(f.validators)
+}
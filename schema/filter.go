@@ -0,0 +1,176 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/weilence/schema-validator/data"
+)
+
+// ErrUnknownPath is returned by Context.Include and Context.Exclude for paths not in the schema
+var ErrUnknownPath = errors.New("unknown path")
+
+// pathFilter is a node of the tree of paths selected for partial validation
+// Children are keyed by field names, [n] for indexes and ElementPathSegment for wildcards
+type pathFilter struct {
+	include  bool
+	leaf     bool
+	children map[string]*pathFilter
+}
+
+// newPathFilter builds the tree of paths, written in the grammar of data.ParsePath with * or [*]
+// matching every element of an array or value of a map, e.g. items[*].sku or items.*.sku
+// Paths must lead to a schema under s
+func newPathFilter(include bool, s Schema, paths []string) (*pathFilter, error) {
+	root := &pathFilter{include: include}
+	for _, path := range paths {
+		segments, err := data.ParsePath(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(segments) == 0 {
+			return nil, fmt.Errorf("%w %q: empty path", data.ErrInvalidPath, path)
+		}
+
+		if !hasPath(s, segments) {
+			return nil, fmt.Errorf("%w %q", ErrUnknownPath, path)
+		}
+
+		node := root
+		for _, seg := range segments {
+			key := filterKey(seg)
+			child, ok := node.children[key]
+			if !ok {
+				child = &pathFilter{include: include}
+				if node.children == nil {
+					node.children = make(map[string]*pathFilter)
+				}
+				node.children[key] = child
+			}
+			node = child
+		}
+		node.leaf = true
+	}
+
+	return root, nil
+}
+
+func filterKey(seg data.PathSegment) string {
+	switch {
+	case seg.IsIndex:
+		return seg.String()
+	case seg.Name == "*":
+		return ElementPathSegment
+	default:
+		return seg.Name
+	}
+}
+
+// hasPath reports whether segments lead to a schema under s, in any branch of composites,
+// variant of unions or branch of conditionals
+func hasPath(s Schema, segments []data.PathSegment) bool {
+	if len(segments) == 0 {
+		return true
+	}
+
+	seg, rest := segments[0], segments[1:]
+	switch s := s.(type) {
+	case *ObjectSchema:
+		if field, ok := s.fields[seg.Name]; ok && !seg.IsIndex && hasPath(field, rest) {
+			return true
+		}
+
+		for _, c := range s.conditionals {
+			if c.then != nil && hasPath(c.then, segments) || c.els != nil && hasPath(c.els, segments) {
+				return true
+			}
+		}
+
+		return false
+	case *ArraySchema:
+		if _, err := strconv.Atoi(seg.Name); !seg.IsIndex && seg.Name != "*" && err != nil {
+			return false
+		}
+
+		return hasPath(s.element, rest)
+	case *MapSchema:
+		if s.value == nil {
+			return len(rest) == 0
+		}
+
+		return hasPath(s.value, rest)
+	case *RefSchema:
+		return s.target != nil && hasPath(s.target, segments)
+	case *CompositeSchema:
+		for _, branch := range s.branches {
+			if hasPath(branch, segments) {
+				return true
+			}
+		}
+
+		return false
+	case *UnionSchema:
+		for _, variant := range s.variants {
+			if hasPath(variant, segments) {
+				return true
+			}
+		}
+
+		return s.fallback != nil && hasPath(s.fallback, segments)
+	default:
+		return false
+	}
+}
+
+// child returns the node matching a segment of a context path, preferring exact matches to wildcards
+// Indexes and keys also match names, so that items.0 selects the same element as items[0]
+func (f *pathFilter) child(segment string) *pathFilter {
+	if child, ok := f.children[segment]; ok {
+		return child
+	}
+
+	if inner, ok := strings.CutPrefix(segment, "["); ok {
+		key := strings.TrimSuffix(inner, "]")
+		if segments, err := data.ParsePath(segment); err == nil && len(segments) == 1 && !segments[0].IsIndex {
+			key = segments[0].Name
+		}
+
+		if child, ok := f.children[key]; ok {
+			return child
+		}
+		return f.children[ElementPathSegment]
+	}
+
+	return nil
+}
+
+// skips reports whether the child at segment is left out of validation
+func (f *pathFilter) skips(segment string) bool {
+	if f == nil {
+		return false
+	}
+
+	child := f.child(segment)
+	if f.include {
+		return child == nil
+	}
+
+	return child != nil && child.leaf
+}
+
+// descend returns the filter of the child at segment, nil when the whole child is validated
+func (f *pathFilter) descend(segment string) *pathFilter {
+	if f == nil {
+		return nil
+	}
+
+	child := f.child(segment)
+	if child == nil || child.leaf {
+		return nil
+	}
+
+	return child
+}
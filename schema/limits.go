@@ -0,0 +1,55 @@
+package schema
+
+import "errors"
+
+// TruncatedCode is the code of the error marking results truncated by ErrorLimits.MaxErrors
+const TruncatedCode = "truncated"
+
+// ErrTruncated is wrapped by the error marking truncated results
+var ErrTruncated = errors.New("too many errors")
+
+// ErrorLimits stops a validation early, for large inputs where every error isn't needed
+type ErrorLimits struct {
+	// FailFast stops the validation at the first error
+	FailFast bool
+
+	// Bail stops the remaining rules of a field, array, map or object after one of them
+	// reported an error, so that email doesn't run once required failed
+	Bail bool
+
+	// MaxErrors stops the validation once it reported MaxErrors errors and finds another,
+	// which is replaced by an error with TruncatedCode; zero means no limit
+	MaxErrors int
+}
+
+// errorLimits holds the state of the limits of a validation, shared by all of its contexts
+type errorLimits struct {
+	ErrorLimits
+
+	errs   *ValidationErrors // errors the limits apply to, not those of separate branches
+	halted bool
+}
+
+func (l *errorLimits) add(err ValidationError) {
+	if l.halted {
+		return
+	}
+
+	if l.MaxErrors > 0 && len(*l.errs) >= l.MaxErrors {
+		l.halted = true
+		l.errs.AddError(ValidationError{
+			Code:   TruncatedCode,
+			Params: []any{l.MaxErrors},
+			Err:    ErrTruncated,
+		})
+		return
+	}
+
+	l.errs.AddError(err)
+	l.halted = l.FailFast
+}
+
+// Truncated reports whether the errors were cut short by ErrorLimits.MaxErrors
+func (r ValidationErrors) Truncated() bool {
+	return len(r) > 0 && r[len(r)-1].Code == TruncatedCode
+}
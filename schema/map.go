@@ -0,0 +1,136 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/weilence/schema-validator/data"
+)
+
+// MapKeyMarker is the path segment appended to an entry path for errors on the map key,
+// e.g. "labels[env].$key", while errors on the value are reported at "labels[env]"
+const MapKeyMarker = "$key"
+
+// MapSchema validates maps, with separate schemas for keys and values
+type MapSchema struct {
+	key   Schema
+	value Schema
+
+	validators []Validator
+}
+
+// NewMap creates a new map schema
+// A nil key or value schema skips validation of keys or values
+func NewMap(key, value Schema) *MapSchema {
+	return &MapSchema{
+		key:        key,
+		value:      value,
+		validators: make([]Validator, 0),
+	}
+}
+
+// Validate validates a map
+func (m *MapSchema) Validate(ctx *Context) error {
+	reported := len(ctx.Errors())
+	for _, validator := range m.validators {
+		if ctx.skipRest || ctx.skipValidators(reported) {
+			break
+		}
+
+		if err := validator.Validate(ctx); err != nil {
+			return err
+		}
+	}
+
+	if ctx.skipRest {
+		return nil
+	}
+
+	switch accessor := ctx.Accessor().(type) {
+	case *data.MapAccessor:
+		return accessor.Iterate(func(key *data.Value, value data.Accessor) error {
+			entry := entrySegment(key.Raw())
+			if ctx.skips(entry) || ctx.halted() {
+				return nil
+			}
+
+			if err := ctx.checkCanceled(entry); err != nil {
+				return err
+			}
+
+			if m.key != nil {
+				keyCtx := ctx.WithChild(entry, m.key, key)
+				keyCtx.path = newContextPath(keyCtx.path, MapKeyMarker)
+				if err := m.key.Validate(keyCtx); err != nil {
+					return err
+				}
+			}
+
+			if m.value != nil {
+				valueCtx := ctx.WithChild(entry, m.value, value)
+				if err := m.value.Validate(valueCtx); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	case *data.Value:
+		// nil map pointer or missing value, only the map-level validators apply
+		// A value of the wrong shape is fine as long as a map-level rule reported it
+		if accessor.IsNilOrZero() || len(ctx.Errors()) > reported {
+			return nil
+		}
+
+		return fmt.Errorf("expected MapAccessor, got primitive value")
+	default:
+		if len(ctx.Errors()) > reported {
+			return nil
+		}
+
+		return fmt.Errorf("expected MapAccessor, got %T", accessor)
+	}
+}
+
+// entrySegment returns the path segment of the entry at key, in the grammar of data.ParsePath
+// Keys are written bare, as in labels[env], unless they hold brackets or quotes
+func entrySegment(key any) string {
+	name := fmt.Sprint(key)
+	if name != "" && !strings.ContainsAny(name, "[]\"'\\") {
+		return "[" + name + "]"
+	}
+
+	return `["` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"]`
+}
+
+// Key returns the schema of the map keys
+func (m *MapSchema) Key() Schema {
+	return m.key
+}
+
+// Value returns the schema of the map values
+func (m *MapSchema) Value() Schema {
+	return m.value
+}
+
+func (m *MapSchema) AddValidator(v Validator) Schema {
+	m.validators = append(m.validators, v)
+	return m
+}
+
+func (m *MapSchema) RemoveValidator(name string) Schema {
+	newValidators := make([]Validator, 0)
+	for _, v := range m.validators {
+		if v.Name() != name {
+			newValidators = append(newValidators, v)
+		}
+	}
+	m.validators = newValidators
+	return m
+}
+
+// Validators returns the validators in the order they run
+func (m *MapSchema) Validators() []Validator {
+	return slices.Clone(m.validators)
+}
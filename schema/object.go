@@ -1,8 +1,11 @@
 package schema
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
 
 	"github.com/weilence/schema-validator/data"
 )
@@ -10,8 +13,11 @@ import (
 // ObjectSchema validates objects/structs/maps
 type ObjectSchema struct {
 	fields       map[string]Schema
+	order        []string          // field names in insertion order
 	fieldNameMap map[string]string // mapping of lower-case field names to actual names
 	validators   []Validator
+	conditionals []*Conditional
+	groupParents map[string][]string // groups inherited by each group
 }
 
 // NewObject creates a new object schema
@@ -24,56 +30,119 @@ func NewObject() *ObjectSchema {
 }
 
 // Validate validates an object
+// Object-level validators run before fields are descended into; a nil or missing
+// object only runs the object-level validators
 func (o *ObjectSchema) Validate(ctx *Context) error {
+	ctx.inheritGroups(o.groupParents)
+
 	accessor := ctx.Accessor()
 	switch oa := accessor.(type) {
 	case data.ObjectAccessor:
+		// The context's schema may only lead to o, as unions do, so the overlay is based on o itself
+		if ctx.overlay == nil || ctx.overlay.base != o {
+			ctx.overlay = newOverlay(o)
+		}
+
 		for _, accessor := range oa.Accessors() {
 			if v, ok := accessor.Raw().(SchemaModifier); ok {
 				v.ModifySchema(ctx)
 			}
 		}
-	case *data.Value:
-		if oa.Kind() == reflect.Invalid {
-			return nil
+
+		// Modifiers may have written to the per-run overlay
+		o = ctx.overlay.Schema()
+	default:
+		if v, ok := oa.(*data.Value); ok && (v.Kind() == reflect.Invalid || (v.Kind() == reflect.Ptr && v.IsNilOrZero())) {
+			return o.validateSelf(ctx)
 		}
-		if oa.Kind() == reflect.Ptr && oa.IsNilOrZero() {
+
+		// A value of the wrong shape is fine as long as an object-level rule reported it
+		reported := len(ctx.Errors())
+		if err := o.validateSelf(ctx); err != nil {
+			return err
+		}
+
+		if ctx.skipRest || len(ctx.Errors()) > reported {
 			return nil
 		}
 
-		return fmt.Errorf("expected object accessor, got primitive value")
-	default:
+		if _, ok := oa.(*data.Value); ok {
+			return fmt.Errorf("expected object accessor, got primitive value")
+		}
+
 		return fmt.Errorf("expected object accessor, got %T", oa)
 	}
 
-	for _, validator := range o.validators {
-		if ctx.skipRest {
+	return o.validateObject(ctx, o.FieldName)
+}
+
+// validateObject validates the fields of an object accessor, then its conditionals
+// fieldName resolves the name used to read a field from the data
+func (o *ObjectSchema) validateObject(ctx *Context, fieldName func(string) string) error {
+	if err := o.validateSelf(ctx); err != nil {
+		return err
+	}
+
+	if ctx.skipRest {
+		return nil
+	}
+
+	for _, name := range o.order {
+		if ctx.halted() {
 			break
 		}
 
-		if err := validator.Validate(ctx); err != nil {
-			return err
+		if ctx.skips(name) {
+			continue
 		}
-	}
 
-	for name, fieldSchema := range o.fields {
-		fieldName := name
-		if mappedName, ok := o.fieldNameMap[name]; ok {
-			fieldName = mappedName
+		if err := ctx.checkCanceled(name); err != nil {
+			return err
 		}
 
-		fieldData, err := accessor.GetField(fieldName)
-		if err != nil {
-			return fmt.Errorf("error accessing field %s: %w", fieldName, err)
+		fieldSchema := o.fields[name]
+		fieldData, err := ctx.accessor.GetField(fieldName(name))
+		if errors.Is(err, data.ErrKeyNotFound) {
+			// Missing map keys validate as absent values, so rules like required can report them
+			fieldData = data.NewValue(nil)
+		} else if err != nil {
+			return fmt.Errorf("error accessing field %s: %w", fieldName(name), err)
 		}
 
 		fieldCtx := ctx.WithChild(name, fieldSchema, fieldData)
+		fieldCtx.absent = errors.Is(err, data.ErrKeyNotFound)
 
 		if err := fieldSchema.Validate(fieldCtx); err != nil {
 			return err
 		}
 	}
 
+	for _, c := range o.conditionals {
+		if ctx.skipRest {
+			break
+		}
+
+		if err := c.validate(ctx, fieldName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateSelf runs the object-level validators
+func (o *ObjectSchema) validateSelf(ctx *Context) error {
+	reported := len(ctx.Errors())
+	for _, validator := range o.validators {
+		if ctx.skipRest || ctx.skipValidators(reported) {
+			break
+		}
+
+		if err := validator.Validate(ctx); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -81,27 +150,86 @@ func (o *ObjectSchema) Field(name string) Schema {
 	return o.fields[name]
 }
 
+// Fields returns the field names in insertion order, which is declaration order for parsed structs
+func (o *ObjectSchema) Fields() []string {
+	return slices.Clone(o.order)
+}
+
 // AddField adds a field schema
 func (o *ObjectSchema) AddField(name string, schema Schema) *ObjectSchema {
 	if oldSchema, ok := o.fields[name]; ok {
 		o.fields[name] = mergeSchema(oldSchema, schema)
 	} else {
 		o.fields[name] = schema
+		o.order = append(o.order, name)
 	}
 
 	return o
 }
 
 func (o *ObjectSchema) RemoveField(name string) *ObjectSchema {
+	if _, ok := o.fields[name]; !ok {
+		return o
+	}
+
 	delete(o.fields, name)
+	o.order = slices.DeleteFunc(o.order, func(n string) bool { return n == name })
 	return o
 }
 
+// FieldName returns the name used to read a field from the data, which is the
+// Go field name for parsed structs and the field name itself when not mapped
+func (o *ObjectSchema) FieldName(name string) string {
+	if fieldName, ok := o.fieldNameMap[name]; ok {
+		return fieldName
+	}
+
+	return name
+}
+
+// FieldNames returns a copy of the mapping of field names to data field names
+func (o *ObjectSchema) FieldNames() map[string]string {
+	return maps.Clone(o.fieldNameMap)
+}
+
 func (o *ObjectSchema) AddFieldName(name string, fieldName string) *ObjectSchema {
 	o.fieldNameMap[name] = fieldName
 	return o
 }
 
+// When adds a conditional validating the object against the branches set on it, after its fields
+// Conditions are evaluated for every run with the context of the object
+func (o *ObjectSchema) When(condition Condition) *Conditional {
+	c := NewConditional(condition)
+	o.conditionals = append(o.conditionals, c)
+	return c
+}
+
+// Conditionals returns the conditionals in the order they run
+func (o *ObjectSchema) Conditionals() []*Conditional {
+	return slices.Clone(o.conditionals)
+}
+
+// InheritGroup makes group inherit the rules of parents when validating the object and
+// everything below it: rules of the parents also run when group is active
+func (o *ObjectSchema) InheritGroup(group string, parents ...string) *ObjectSchema {
+	if o.groupParents == nil {
+		o.groupParents = make(map[string][]string)
+	}
+
+	for _, parent := range parents {
+		if !slices.Contains(o.groupParents[group], parent) {
+			o.groupParents[group] = append(slices.Clip(o.groupParents[group]), parent)
+		}
+	}
+	return o
+}
+
+// GroupParents returns the groups inherited by each group
+func (o *ObjectSchema) GroupParents() map[string][]string {
+	return maps.Clone(o.groupParents)
+}
+
 func (o *ObjectSchema) AddValidator(v Validator) Schema {
 	o.validators = append(o.validators, v)
 	return o
@@ -118,35 +246,119 @@ func (o *ObjectSchema) RemoveValidator(name string) Schema {
 	return o
 }
 
+// Clone returns a shallow copy of the object schema
+// Field schemas are shared with the original, while fields and validators can be changed independently
+func (o *ObjectSchema) Clone() *ObjectSchema {
+	return &ObjectSchema{
+		fields:       maps.Clone(o.fields),
+		order:        slices.Clone(o.order),
+		fieldNameMap: maps.Clone(o.fieldNameMap),
+		validators:   slices.Clone(o.validators),
+		conditionals: slices.Clone(o.conditionals),
+		groupParents: maps.Clone(o.groupParents),
+	}
+}
+
+// mergeSchema returns a new schema combining s1 and s2; neither input is modified
+// Schemas of different kinds can't be combined, so s2 replaces s1
 func mergeSchema(s1, s2 Schema) Schema {
+	if reflect.TypeOf(s1) != reflect.TypeOf(s2) {
+		return s2
+	}
+
 	switch s := s1.(type) {
 	case *FieldSchema:
 		fs2 := s2.(*FieldSchema)
-		for _, v := range fs2.validators {
-			s.AddValidator(v)
+		return &FieldSchema{
+			validators: slices.Concat(s.validators, fs2.validators),
 		}
-		return s
 	case *ArraySchema:
 		as2 := s2.(*ArraySchema)
-		s.element = mergeSchema(s.element, as2.element)
-		for _, v := range as2.validators {
-			s.AddValidator(v)
+		return &ArraySchema{
+			element:    mergeOptionalSchema(s.element, as2.element),
+			validators: slices.Concat(s.validators, as2.validators),
+		}
+	case *RefSchema:
+		rs2 := s2.(*RefSchema)
+		if s.target != nil && rs2.target != nil && s.target != rs2.target {
+			return s2
+		}
+
+		target := s.target
+		if target == nil {
+			target = rs2.target
+		}
+
+		return &RefSchema{
+			target:     target,
+			validators: slices.Concat(s.validators, rs2.validators),
+		}
+	case *CompositeSchema:
+		cs2 := s2.(*CompositeSchema)
+		if s.mode != AllOf || cs2.mode != AllOf {
+			// Both have to pass, whatever their modes
+			return NewAllOf(s1, s2)
+		}
+
+		return &CompositeSchema{
+			mode:       AllOf,
+			branches:   slices.Concat(s.branches, cs2.branches),
+			validators: slices.Concat(s.validators, cs2.validators),
+		}
+	case *UnionSchema:
+		us2 := s2.(*UnionSchema)
+		if s.discriminator != us2.discriminator {
+			return s2
+		}
+
+		merged := &UnionSchema{
+			discriminator: s.discriminator,
+			variants:      maps.Clone(s.variants),
+			order:         slices.Clone(s.order),
+			fallback:      mergeOptionalSchema(s.fallback, us2.fallback),
+			validators:    slices.Concat(s.validators, us2.validators),
+		}
+		for _, key := range us2.order {
+			merged.AddVariant(key, mergeOptionalSchema(merged.variants[key], us2.variants[key]))
+		}
+		return merged
+	case *MapSchema:
+		ms2 := s2.(*MapSchema)
+		return &MapSchema{
+			key:        mergeOptionalSchema(s.key, ms2.key),
+			value:      mergeOptionalSchema(s.value, ms2.value),
+			validators: slices.Concat(s.validators, ms2.validators),
 		}
-		return s
 	case *ObjectSchema:
 		os2 := s2.(*ObjectSchema)
-		for name, fieldSchema2 := range os2.fields {
-			if fieldSchema1, ok := s.fields[name]; ok {
-				s.fields[name] = mergeSchema(fieldSchema1, fieldSchema2)
-			} else {
-				s.fields[name] = fieldSchema2
-			}
+		merged := s.Clone()
+		for _, name := range os2.order {
+			merged.AddField(name, os2.fields[name])
 		}
-		for _, v := range os2.validators {
-			s.AddValidator(v)
+		merged.validators = slices.Concat(merged.validators, os2.validators)
+		merged.conditionals = slices.Concat(merged.conditionals, os2.conditionals)
+		for group, parents := range os2.groupParents {
+			merged.InheritGroup(group, parents...)
 		}
-		return s
+		return merged
 	default:
-		panic("unknown schema type")
+		return s2
 	}
 }
+
+// mergeOptionalSchema merges two schemas where either may be nil
+func mergeOptionalSchema(s1, s2 Schema) Schema {
+	if s1 == nil {
+		return s2
+	}
+	if s2 == nil {
+		return s1
+	}
+
+	return mergeSchema(s1, s2)
+}
+
+// Validators returns the validators in the order they run
+func (o *ObjectSchema) Validators() []Validator {
+	return slices.Clone(o.validators)
+}
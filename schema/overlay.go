@@ -0,0 +1,71 @@
+package schema
+
+// Overlay is a copy-on-write view of an ObjectSchema scoped to a single validation run.
+// Reads go to the compiled schema until the first write, which clones it, so
+// SchemaModifier implementations never mutate the schema shared by other runs.
+type Overlay struct {
+	base   *ObjectSchema
+	copied *ObjectSchema
+}
+
+func newOverlay(base *ObjectSchema) *Overlay {
+	return &Overlay{base: base}
+}
+
+// Schema returns the effective object schema for the current run
+func (o *Overlay) Schema() *ObjectSchema {
+	if o.copied != nil {
+		return o.copied
+	}
+
+	return o.base
+}
+
+func (o *Overlay) writable() *ObjectSchema {
+	if o.copied == nil {
+		o.copied = o.base.Clone()
+	}
+
+	return o.copied
+}
+
+// Field returns the effective schema of a field
+func (o *Overlay) Field(name string) Schema {
+	return o.Schema().Field(name)
+}
+
+// AddField adds a field schema, merging it with an existing one of the same name
+func (o *Overlay) AddField(name string, schema Schema) *Overlay {
+	o.writable().AddField(name, schema)
+	return o
+}
+
+// ReplaceField sets a field schema, discarding any existing one of the same name
+func (o *Overlay) ReplaceField(name string, schema Schema) *Overlay {
+	o.writable().RemoveField(name).AddField(name, schema)
+	return o
+}
+
+// RemoveField removes a field schema
+func (o *Overlay) RemoveField(name string) *Overlay {
+	o.writable().RemoveField(name)
+	return o
+}
+
+// AddFieldName sets the mapping for an object field
+func (o *Overlay) AddFieldName(name string, fieldName string) *Overlay {
+	o.writable().AddFieldName(name, fieldName)
+	return o
+}
+
+// AddValidator adds an object-level validator
+func (o *Overlay) AddValidator(v Validator) *Overlay {
+	o.writable().AddValidator(v)
+	return o
+}
+
+// RemoveValidator removes object-level validators by name
+func (o *Overlay) RemoveValidator(name string) *Overlay {
+	o.writable().RemoveValidator(name)
+	return o
+}
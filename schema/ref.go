@@ -0,0 +1,100 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+)
+
+// RefSchema is a lazily resolved reference to another schema
+// It is used for recursive types, where a schema has to refer to itself before it is complete
+type RefSchema struct {
+	target Schema
+
+	validators []Validator
+}
+
+// NewRef creates an unresolved reference schema
+func NewRef() *RefSchema {
+	return &RefSchema{
+		validators: make([]Validator, 0),
+	}
+}
+
+// Resolve sets the schema the reference points to
+func (r *RefSchema) Resolve(target Schema) *RefSchema {
+	r.target = target
+	return r
+}
+
+// Target returns the referenced schema, nil if not resolved yet
+func (r *RefSchema) Target() Schema {
+	return r.target
+}
+
+// Cycles reports whether following targets from the reference only ever reaches other
+// references, which would recurse forever when validated
+func (r *RefSchema) Cycles() bool {
+	visited := make(map[*RefSchema]bool)
+	for ref := r; !visited[ref]; {
+		visited[ref] = true
+
+		next, ok := ref.target.(*RefSchema)
+		if !ok {
+			return false
+		}
+		ref = next
+	}
+
+	return true
+}
+
+// Validate runs the reference's own validators, then validates against the referenced schema
+func (r *RefSchema) Validate(ctx *Context) error {
+	if r.target == nil {
+		return fmt.Errorf("unresolved schema reference at %s", ctx.Path())
+	}
+
+	reported := len(ctx.Errors())
+	for _, validator := range r.validators {
+		if ctx.skipRest {
+			return nil
+		}
+
+		if ctx.skipValidators(reported) {
+			break
+		}
+
+		if err := validator.Validate(ctx); err != nil {
+			return err
+		}
+	}
+
+	if ctx.skipRest {
+		return nil
+	}
+
+	ctx.schema = r.target
+	return r.target.Validate(ctx)
+}
+
+// AddValidator adds a validator to the reference itself, leaving the shared target untouched
+func (r *RefSchema) AddValidator(v Validator) Schema {
+	r.validators = append(r.validators, v)
+	return r
+}
+
+func (r *RefSchema) RemoveValidator(name string) Schema {
+	newValidators := make([]Validator, 0)
+	for _, v := range r.validators {
+		if v.Name() != name {
+			newValidators = append(newValidators, v)
+		}
+	}
+	r.validators = newValidators
+	return r
+}
+
+// Validators returns the validators in the order they run
+func (r *RefSchema) Validators() []Validator {
+	return slices.Clone(r.validators)
+}
@@ -7,7 +7,9 @@ type SchemaModifier interface {
 	// ctx provides access to:
 	//   - validation context (path, parent, root)
 	//   - current object's accessor (via ctx.AsObject())
-	//   - current ObjectSchema (via ctx.ObjectSchema())
+	//   - a per-run, copy-on-write view of the current ObjectSchema (via ctx.Overlay())
+	// Changes must go through ctx.Overlay(); the schema returned by ctx.Schema() is shared
+	// by every validation run and must not be modified
 	ModifySchema(ctx *Context)
 }
 
@@ -0,0 +1,246 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/weilence/schema-validator/data"
+)
+
+// UnknownDiscriminatorCode is the error code reported when a union has no variant for a value and no fallback
+const UnknownDiscriminatorCode = "unknown_discriminator"
+
+// UnionSchema validates a value against one of several variant schemas, selected by the
+// value at a discriminator path or, for type unions, by the dynamic Go type of the value
+type UnionSchema struct {
+	discriminator string // empty for type unions
+	variants      map[string]Schema
+	order         []string // variant keys in insertion order
+	fallback      Schema
+
+	validators []Validator
+}
+
+// NewUnion creates a union selecting variants by the value at the discriminator path,
+// e.g. "type" or "meta.kind", relative to the validated value
+// For structs the path is resolved through the field names mapped by the variants, see ObjectSchema.FieldName
+func NewUnion(discriminator string) *UnionSchema {
+	return &UnionSchema{
+		discriminator: discriminator,
+		variants:      make(map[string]Schema),
+		validators:    make([]Validator, 0),
+	}
+}
+
+// NewTypeUnion creates a union selecting variants by the dynamic type of the value, keyed by TypeKey
+func NewTypeUnion() *UnionSchema {
+	return NewUnion("")
+}
+
+// TypeKey returns the variant key of a type in type unions, pointers select the variant of their element type
+// Named types are keyed by their package path and name, so types of the same name in different
+// packages get different keys
+func TypeKey(rt reflect.Type) string {
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	if rt.Name() != "" && rt.PkgPath() != "" {
+		return rt.PkgPath() + "." + rt.Name()
+	}
+
+	return rt.String()
+}
+
+// KindKey returns the variant key matching every type of a kind in type unions, such as all maps,
+// used for values whose type has no variant of its own
+func KindKey(kind reflect.Kind) string {
+	return "kind:" + kind.String()
+}
+
+// Discriminator returns the discriminator path, empty for type unions
+func (u *UnionSchema) Discriminator() string {
+	return u.discriminator
+}
+
+// AddVariant sets the schema used when the discriminator value formats as key
+func (u *UnionSchema) AddVariant(key string, s Schema) *UnionSchema {
+	if _, ok := u.variants[key]; !ok {
+		u.order = append(u.order, key)
+	}
+	u.variants[key] = s
+
+	return u
+}
+
+// Variant returns the schema of a discriminator value, nil if there is none
+func (u *UnionSchema) Variant(key string) Schema {
+	return u.variants[key]
+}
+
+// Variants returns the variant keys in insertion order
+func (u *UnionSchema) Variants() []string {
+	return slices.Clone(u.order)
+}
+
+// SetFallback sets the schema used for discriminator values without a variant
+// Without a fallback they are reported with UnknownDiscriminatorCode
+func (u *UnionSchema) SetFallback(s Schema) *UnionSchema {
+	u.fallback = s
+	return u
+}
+
+// Fallback returns the schema used for discriminator values without a variant, nil if not set
+func (u *UnionSchema) Fallback() Schema {
+	return u.fallback
+}
+
+// Validate runs the union's own validators, then validates the value against the selected variant
+// A nil or missing value only runs the union's own validators
+func (u *UnionSchema) Validate(ctx *Context) error {
+	reported := len(ctx.Errors())
+	for _, validator := range u.validators {
+		if ctx.skipRest {
+			return nil
+		}
+
+		if ctx.skipValidators(reported) {
+			break
+		}
+
+		if err := validator.Validate(ctx); err != nil {
+			return err
+		}
+	}
+
+	if ctx.skipRest {
+		return nil
+	}
+
+	if v, ok := ctx.Accessor().(*data.Value); ok && (v.Kind() == reflect.Invalid || (v.Kind() == reflect.Pointer && v.IsNilOrZero())) {
+		return nil
+	}
+
+	key, err := u.key(ctx)
+	if err != nil {
+		return err
+	}
+
+	variant, ok := u.variants[key]
+	if !ok && u.discriminator == "" {
+		variant, ok = u.variants[KindKey(indirectKind(ctx.Accessor().Raw()))]
+	}
+	if !ok {
+		variant = u.fallback
+	}
+
+	if variant == nil {
+		path := ctx.path
+		if u.discriminator != "" {
+			path = newContextPath(path, u.discriminator)
+		}
+
+		ctx.AddError(ValidationError{
+			Path:   path.String(),
+			Code:   UnknownDiscriminatorCode,
+			Params: []any{key},
+			Err:    ErrCheckFailed,
+		})
+		return nil
+	}
+
+	return variant.Validate(ctx.branch(variant, nil))
+}
+
+// indirectKind returns the kind of a value, of the element for pointers
+func indirectKind(raw any) reflect.Kind {
+	rt := reflect.TypeOf(raw)
+	if rt == nil {
+		return reflect.Invalid
+	}
+
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	return rt.Kind()
+}
+
+// key returns the variant key of the validated value
+func (u *UnionSchema) key(ctx *Context) (string, error) {
+	if u.discriminator == "" {
+		raw := ctx.Accessor().Raw()
+		if raw == nil {
+			return "", nil
+		}
+
+		return TypeKey(reflect.TypeOf(raw)), nil
+	}
+
+	if _, ok := ctx.Accessor().(*data.Value); ok {
+		// Values other than objects have no discriminator
+		return "", nil
+	}
+
+	segments, err := data.ParsePath(u.discriminator)
+	if err != nil {
+		return "", fmt.Errorf("error reading discriminator %s: %w", u.discriminator, err)
+	}
+
+	// Struct fields are named as in the variants, such as Type for a type field parsed from a
+	// json tag, so the path is mapped through each variant until one reaches a value
+	candidates := make([]Schema, 0, len(u.order)+2)
+	for _, key := range u.order {
+		candidates = append(candidates, u.variants[key])
+	}
+	candidates = append(candidates, u.fallback, nil)
+
+	for _, candidate := range candidates {
+		mapped := slices.Clone(segments)
+		mapFieldNames(candidate, mapped)
+
+		acc, err := data.GetPath(ctx.Accessor(), mapped)
+		if errors.Is(err, data.ErrKeyNotFound) || errors.Is(err, data.ErrFieldNotFound) {
+			continue
+		} else if err != nil {
+			return "", fmt.Errorf("error reading discriminator %s: %w", u.discriminator, err)
+		}
+
+		v, err := acc.GetValue("")
+		if err != nil {
+			return "", fmt.Errorf("error reading discriminator %s: %w", u.discriminator, err)
+		}
+
+		if v.Any() == nil {
+			return "", nil
+		}
+
+		return fmt.Sprint(v.Any()), nil
+	}
+
+	// A missing discriminator selects the fallback like any unknown value
+	return "", nil
+}
+
+func (u *UnionSchema) AddValidator(v Validator) Schema {
+	u.validators = append(u.validators, v)
+	return u
+}
+
+func (u *UnionSchema) RemoveValidator(name string) Schema {
+	newValidators := make([]Validator, 0)
+	for _, v := range u.validators {
+		if v.Name() != name {
+			newValidators = append(newValidators, v)
+		}
+	}
+	u.validators = newValidators
+	return u
+}
+
+// Validators returns the union's own validators in the order they run
+func (u *UnionSchema) Validators() []Validator {
+	return slices.Clone(u.validators)
+}
@@ -1,7 +1,16 @@
 package schema
 
+// DefaultGroup is the validation group active when no groups are set
+const DefaultGroup = "default"
+
 type Validator interface {
 	Name() string
 	Params() []any
 	Validate(ctx *Context) error
 }
+
+// GroupedValidator is a validator limited to validation groups, see Context.SetGroups
+type GroupedValidator interface {
+	Validator
+	Groups() []string
+}
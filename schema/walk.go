@@ -0,0 +1,107 @@
+package schema
+
+import "errors"
+
+// SkipChildren can be returned by a Visitor to skip the children of the current node
+var SkipChildren = errors.New("skip children")
+
+// ElementPathSegment is the path segment used for array elements and map values when walking a schema
+const ElementPathSegment = "[*]"
+
+// Visitor is called for every node of a schema tree with its path
+// Paths use the same format as validation errors, with ElementPathSegment in place of indexes and keys
+type Visitor func(path string, s Schema) error
+
+// Walk visits s and every schema nested in it in depth-first order
+// Object fields are visited in insertion order. A reference is visited, followed by its
+// target at the same path, unless the target is already being walked further up as in recursive types.
+// Branches of a composite or conditional and variants of a union are visited at their parent's path
+func Walk(s Schema, visitor Visitor) error {
+	w := &walker{
+		visitor: visitor,
+		active:  make(map[Schema]bool),
+	}
+
+	err := w.walk(nil, s)
+	if errors.Is(err, SkipChildren) {
+		return nil
+	}
+
+	return err
+}
+
+type walker struct {
+	visitor Visitor
+	active  map[Schema]bool
+}
+
+func (w *walker) walk(path contextPath, s Schema) error {
+	if s == nil {
+		return nil
+	}
+
+	if err := w.visitor(path.String(), s); err != nil {
+		if errors.Is(err, SkipChildren) {
+			return nil
+		}
+
+		return err
+	}
+
+	// Every node on the way down is active, as recursive types may pass through any kind
+	w.active[s] = true
+	defer delete(w.active, s)
+
+	switch s := s.(type) {
+	case *ObjectSchema:
+		for _, name := range s.order {
+			if err := w.walk(newContextPath(path, name), s.fields[name]); err != nil {
+				return err
+			}
+		}
+
+		// Branches of conditionals are visited at the object's own path
+		for _, c := range s.conditionals {
+			if err := w.walk(path, c.then); err != nil {
+				return err
+			}
+			if err := w.walk(path, c.els); err != nil {
+				return err
+			}
+		}
+	case *ArraySchema:
+		return w.walk(newContextPath(path, ElementPathSegment), s.element)
+	case *MapSchema:
+		entryPath := newContextPath(path, ElementPathSegment)
+		if err := w.walk(newContextPath(entryPath, MapKeyMarker), s.key); err != nil {
+			return err
+		}
+
+		return w.walk(entryPath, s.value)
+	case *CompositeSchema:
+		// Branches are visited at the composite's own path
+		for _, branch := range s.branches {
+			if err := w.walk(path, branch); err != nil {
+				return err
+			}
+		}
+	case *UnionSchema:
+		// Variants, then the fallback, are visited at the union's own path
+		for _, key := range s.order {
+			if err := w.walk(path, s.variants[key]); err != nil {
+				return err
+			}
+		}
+
+		return w.walk(path, s.fallback)
+	case *RefSchema:
+		// The target is visited at the reference's own path
+		if w.active[s.target] {
+			return nil
+		}
+
+		return w.walk(path, s.target)
+	}
+
+	return nil
+}
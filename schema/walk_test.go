@@ -0,0 +1,116 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubValidator struct {
+	name   string
+	params []any
+}
+
+func (v stubValidator) Name() string            { return v.name }
+func (v stubValidator) Params() []any           { return v.params }
+func (v stubValidator) Validate(*Context) error { return nil }
+
+func TestWalk(t *testing.T) {
+	node := NewObject()
+	ref := NewRef().Resolve(node)
+	node.AddField("name", NewField().AddValidator(stubValidator{name: "required"})).
+		AddField("children", NewArray(ref)).
+		AddField("labels", NewMap(NewField(), NewField())).
+		AddFieldName("name", "Name")
+
+	var paths []string
+	err := Walk(node, func(path string, s Schema) error {
+		paths = append(paths, path)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"",
+		"name",
+		"children",
+		"children[*]",
+		"labels",
+		"labels[*].$key",
+		"labels[*]",
+	}, paths)
+
+	paths = nil
+	err = Walk(node, func(path string, s Schema) error {
+		paths = append(paths, path)
+		if _, ok := s.(*ArraySchema); ok {
+			return SkipChildren
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NotContains(t, paths, "children[*]")
+
+	stop := errors.New("stop")
+	err = Walk(node, func(path string, s Schema) error {
+		if path == "children" {
+			return stop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, stop)
+}
+
+func TestWalk_RecursiveCollections(t *testing.T) {
+	tree := NewRef()
+	tree.Resolve(NewArray(tree))
+
+	dict := NewRef()
+	dict.Resolve(NewMap(NewField(), dict))
+
+	for s, expected := range map[Schema][]string{
+		tree: {"", "", "[*]"},
+		dict: {"", "", "[*].$key", "[*]"},
+	} {
+		var paths []string
+		err := Walk(s, func(path string, s Schema) error {
+			paths = append(paths, path)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, paths)
+	}
+}
+
+func TestWalk_Conditional(t *testing.T) {
+	node := NewObject().AddField("country", NewField())
+	node.When(Eq("country", "US")).
+		Then(NewObject().AddField("zipCode", NewField())).
+		Else(NewField())
+
+	var paths []string
+	err := Walk(node, func(path string, s Schema) error {
+		paths = append(paths, path)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"", "country", "", "zipCode", ""}, paths)
+}
+
+func TestIntrospection(t *testing.T) {
+	field := NewField().AddValidator(stubValidator{name: "min", params: []any{1}}).(*FieldSchema)
+	validators := field.Validators()
+	assert.Len(t, validators, 1)
+	assert.Equal(t, "min", validators[0].Name())
+	assert.Equal(t, []any{1}, validators[0].Params())
+
+	// Returned slices are copies
+	validators[0] = stubValidator{name: "max"}
+	assert.Equal(t, "min", field.Validators()[0].Name())
+
+	obj := NewObject().AddField("b", field).AddField("a", NewField()).AddFieldName("a", "A")
+	assert.Equal(t, []string{"b", "a"}, obj.Fields())
+	assert.Equal(t, "A", obj.FieldName("a"))
+	assert.Equal(t, "b", obj.FieldName("b"))
+	assert.Equal(t, map[string]string{"a": "A"}, obj.FieldNames())
+}
@@ -8,12 +8,22 @@ import (
 type Rule struct {
 	Name   string
 	Params []string
+
+	// Groups limits the rule to validation groups, as in update:required or create,update:min=3,
+	// nil for rules of every group
+	Groups []string
+
+	// Children holds the nested rules of a group such as anyOf(hexcolor|rgb), nil for plain rules
+	Children []Rule
 }
 
 type Config struct {
 	RuleSplitter       rune
 	NameParamSeparator rune
 	ParamsSeparator    rune
+
+	// GroupSeparator ends the groups prefixed to a rule, zero to disable groups
+	GroupSeparator rune
 }
 
 func DefaultConfig() Config {
@@ -21,11 +31,15 @@ func DefaultConfig() Config {
 		RuleSplitter:       '|',
 		NameParamSeparator: '=',
 		ParamsSeparator:    ',',
+		GroupSeparator:     ':',
 	}
 }
 
 type Parser struct {
 	cfg Config
+
+	// textParams is the function passed to ParseFunc, nil for Parse
+	textParams func(name string) bool
 }
 
 func NewParser(cfg Config) *Parser {
@@ -40,6 +54,19 @@ func Parse(tag string) []Rule {
 }
 
 func (p *Parser) Parse(tag string) []Rule {
+	return p.ParseFunc(tag, nil)
+}
+
+// ParseFunc is like Parse, keeping whole the params of the rules for which textParams returns true,
+// such as expressions: they are a single param, and RuleSplitter inside parentheses or double quotes
+// or doubled, as in ||, doesn't end them
+func (p *Parser) ParseFunc(tag string, textParams func(name string) bool) []Rule {
+	parser := *p
+	parser.textParams = textParams
+	return parser.parse(tag)
+}
+
+func (p *Parser) parse(tag string) []Rule {
 	if tag == "" {
 		return nil
 	}
@@ -47,15 +74,57 @@ func (p *Parser) Parse(tag string) []Rule {
 	rules := make([]Rule, 0)
 	currentRule := ""
 	inParam := false
+	depth := 0 // nesting of group parentheses, whose content is parsed as a rule list of its own
+
+	// state of a text param, see ParseFunc
+	text, quoted, textDepth := false, false, 0
+
+	endRule := func() {
+		if currentRule != "" {
+			rules = append(rules, p.parseRule(currentRule))
+			currentRule = ""
+		}
+		inParam, text, quoted, textDepth = false, false, false, 0
+	}
 
 	for i := 0; i < len(tag); i++ {
 		ch := tag[i]
 
-		if ch == byte(p.cfg.NameParamSeparator) {
+		if depth > 0 {
+			switch ch {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			currentRule += string(ch)
+		} else if ch == '(' && !inParam && isValidatorName(p.withoutGroups(currentRule)) {
+			depth++
+			currentRule += string(ch)
+		} else if text && (ch == '"' || quoted || ch == '(' || ch == ')') {
+			switch {
+			case ch == '"':
+				quoted = !quoted
+			case quoted:
+			case ch == '(':
+				textDepth++
+			case textDepth > 0:
+				textDepth--
+			}
+			currentRule += string(ch)
+		} else if ch == byte(p.cfg.NameParamSeparator) {
+			if !inParam {
+				text = p.isTextRule(p.withoutGroups(currentRule))
+			}
 			inParam = true
 			currentRule += string(ch)
 		} else if ch == byte(p.cfg.RuleSplitter) {
-			if inParam {
+			if text && textDepth > 0 {
+				currentRule += string(ch)
+			} else if text && i+1 < len(tag) && tag[i+1] == ch {
+				currentRule += tag[i : i+2]
+				i++
+			} else if inParam {
 				nextPart := ""
 				for j := i + 1; j < len(tag); j++ {
 					if tag[j] == byte(p.cfg.RuleSplitter) {
@@ -64,41 +133,87 @@ func (p *Parser) Parse(tag string) []Rule {
 					nextPart += string(tag[j])
 				}
 
-				if !slices.Contains([]byte(nextPart), byte(p.cfg.NameParamSeparator)) && !isValidatorName(nextPart) {
+				nextPart = p.withoutGroups(nextPart)
+				if !slices.Contains([]byte(nextPart), byte(p.cfg.NameParamSeparator)) && !isValidatorName(nextPart) && !isGroupStart(nextPart) {
 					currentRule += string(ch)
 				} else {
-					inParam = false
-					if currentRule != "" {
-						rules = append(rules, p.parseRule(currentRule))
-						currentRule = ""
-					}
+					endRule()
 				}
 			} else {
-				if currentRule != "" {
-					rules = append(rules, p.parseRule(currentRule))
-					currentRule = ""
-				}
+				endRule()
 			}
 		} else {
 			currentRule += string(ch)
 		}
 	}
 
-	if currentRule != "" {
-		rules = append(rules, p.parseRule(currentRule))
-	}
-
+	endRule()
 	return rules
 }
 
+// isTextRule reports whether the params of the named rule are kept whole, see ParseFunc
+func (p *Parser) isTextRule(name string) bool {
+	return p.textParams != nil && p.textParams(strings.TrimSpace(name))
+}
+
 func (p *Parser) parseRule(ruleStr string) Rule {
-	ruleStr = strings.TrimSpace(ruleStr)
+	groups, ruleStr := p.cutGroups(strings.TrimSpace(ruleStr))
+	r := p.parseUngroupedRule(ruleStr)
+	r.Groups = groups
+	return r
+}
+
+// cutGroups splits the groups prefixed to a rule, such as create,update in create,update:min=3
+func (p *Parser) cutGroups(ruleStr string) ([]string, string) {
+	if p.cfg.GroupSeparator == 0 {
+		return nil, ruleStr
+	}
+
+	prefix, rest, ok := strings.Cut(ruleStr, string(p.cfg.GroupSeparator))
+	if !ok {
+		return nil, ruleStr
+	}
+
+	groups := strings.Split(prefix, string(p.cfg.ParamsSeparator))
+	for i, group := range groups {
+		groups[i] = strings.TrimSpace(group)
+		if !isValidatorName(groups[i]) {
+			// The separator belongs to the params, as in regex=^a:b$
+			return nil, ruleStr
+		}
+	}
+
+	return groups, strings.TrimSpace(rest)
+}
+
+// withoutGroups returns a rule without the groups prefixed to it
+func (p *Parser) withoutGroups(ruleStr string) string {
+	_, rest := p.cutGroups(strings.TrimSpace(ruleStr))
+	return rest
+}
+
+func (p *Parser) parseUngroupedRule(ruleStr string) Rule {
+
+	if name, inner, ok := strings.Cut(ruleStr, "("); ok && isValidatorName(name) && strings.HasSuffix(inner, ")") {
+		children := p.parse(strings.TrimSuffix(inner, ")"))
+		if children == nil {
+			children = []Rule{}
+		}
+
+		return Rule{
+			Name:     strings.TrimSpace(name),
+			Params:   []string{},
+			Children: children,
+		}
+	}
 
 	if before, after, ok := strings.Cut(ruleStr, string(p.cfg.NameParamSeparator)); ok {
 		name := strings.TrimSpace(before)
 		raw := strings.TrimSpace(after)
 		parts := []string{}
-		if raw != "" {
+		if raw != "" && p.isTextRule(name) {
+			parts = append(parts, raw)
+		} else if raw != "" {
 			for _, param := range strings.Split(raw, string(p.cfg.ParamsSeparator)) {
 				tp := strings.TrimSpace(param)
 				if tp != "" {
@@ -118,6 +233,12 @@ func (p *Parser) parseRule(ruleStr string) Rule {
 	}
 }
 
+// isGroupStart reports whether s starts with a group such as anyOf(
+func isGroupStart(s string) bool {
+	name, _, ok := strings.Cut(s, "(")
+	return ok && isValidatorName(name)
+}
+
 func isValidatorName(s string) bool {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -0,0 +1,107 @@
+package tag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		tag      string
+		expected []Rule
+	}{
+		{"empty", "", nil},
+		{"plain", "required", []Rule{{Name: "required", Params: []string{}}}},
+		{"params", "required|min=3|between=1, 5", []Rule{
+			{Name: "required", Params: []string{}},
+			{Name: "min", Params: []string{"3"}},
+			{Name: "between", Params: []string{"1", "5"}},
+		}},
+		{"splitter in params", "regex=^(a|b)$|max=3", []Rule{
+			{Name: "regex", Params: []string{"^(a|b)$"}},
+			{Name: "max", Params: []string{"3"}},
+		}},
+		{"parentheses in params", "excludesall=)(,", []Rule{{Name: "excludesall", Params: []string{")("}}}},
+		{"separator in parentheses", "startswith=foo(bar,baz)", []Rule{{Name: "startswith", Params: []string{"foo(bar", "baz)"}}}},
+		{"separator in quotes", `oneof=a "b,c" d`, []Rule{{Name: "oneof", Params: []string{`a "b`, `c" d`}}}},
+		{"doubled splitter", "oneof=a,b||c", []Rule{
+			{Name: "oneof", Params: []string{"a", "b|"}},
+			{Name: "c", Params: []string{}},
+		}},
+		{"groups", "create,update:required|update:min=3", []Rule{
+			{Name: "required", Params: []string{}, Groups: []string{"create", "update"}},
+			{Name: "min", Params: []string{"3"}, Groups: []string{"update"}},
+		}},
+		{"group separator in params", "regex=^a:b$", []Rule{{Name: "regex", Params: []string{"^a:b$"}}}},
+		{"combinator", "anyOf(hexcolor|rgb)|max=7", []Rule{
+			{Name: "anyOf", Params: []string{}, Children: []Rule{
+				{Name: "hexcolor", Params: []string{}},
+				{Name: "rgb", Params: []string{}},
+			}},
+			{Name: "max", Params: []string{"7"}},
+		}},
+		{"nested combinators", "not(anyOf(min=1|max=2)|len=3)", []Rule{
+			{Name: "not", Params: []string{}, Children: []Rule{
+				{Name: "anyOf", Params: []string{}, Children: []Rule{
+					{Name: "min", Params: []string{"1"}},
+					{Name: "max", Params: []string{"2"}},
+				}},
+				{Name: "len", Params: []string{"3"}},
+			}},
+		}},
+		{"combinator with groups", "update:anyOf(email|e164)", []Rule{
+			{Name: "anyOf", Params: []string{}, Groups: []string{"update"}, Children: []Rule{
+				{Name: "email", Params: []string{}},
+				{Name: "e164", Params: []string{}},
+			}},
+		}},
+		{"empty combinator", "anyOf()", []Rule{{Name: "anyOf", Params: []string{}, Children: []Rule{}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Parse(tt.tag))
+		})
+	}
+}
+
+func TestParseFunc(t *testing.T) {
+	textParams := func(name string) bool { return name == "expr" }
+
+	tests := []struct {
+		name     string
+		tag      string
+		expected []Rule
+	}{
+		{"separator", "expr=len(a, 1) > 0|max=3", []Rule{
+			{Name: "expr", Params: []string{"len(a, 1) > 0"}},
+			{Name: "max", Params: []string{"3"}},
+		}},
+		{"doubled splitter", "expr=a || b|max=3", []Rule{
+			{Name: "expr", Params: []string{"a || b"}},
+			{Name: "max", Params: []string{"3"}},
+		}},
+		{"splitter in quotes and parentheses", `expr=contains(a, "x|email")|(b|c)`, []Rule{
+			{Name: "expr", Params: []string{`contains(a, "x|email")|(b|c)`}},
+		}},
+		{"groups", "update:expr=a, b", []Rule{{Name: "expr", Params: []string{"a, b"}, Groups: []string{"update"}}}},
+		{"in combinator", "anyOf(expr=a || b|email)", []Rule{
+			{Name: "anyOf", Params: []string{}, Children: []Rule{
+				{Name: "expr", Params: []string{"a || b"}},
+				{Name: "email", Params: []string{}},
+			}},
+		}},
+		{"other rules", "oneof=a,b||c", []Rule{
+			{Name: "oneof", Params: []string{"a", "b|"}},
+			{Name: "c", Params: []string{}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NewParser(DefaultConfig()).ParseFunc(tt.tag, textParams))
+		})
+	}
+}
@@ -1,19 +1,38 @@
 package validator
 
 import (
+	"context"
+	"fmt"
 	"reflect"
+	"slices"
 
+	"github.com/weilence/schema-validator/builder"
 	"github.com/weilence/schema-validator/data"
 	"github.com/weilence/schema-validator/schema"
 )
 
+// defaultParseConfig is shared by New and ValidateStruct when no options are given,
+// so schemas compiled through it are cached for the life of the process
+var defaultParseConfig = builder.NewParseConfig()
+
 // Validator is the main entry point for validation
 type Validator struct {
 	schema schema.Schema
+	limits schema.ErrorLimits
+	groups []string
+
+	collectInternal bool
 }
 
+// New creates a validator from the struct tags of prototype
+// Without options the compiled schema is cached by type and shared with ValidateStruct
 func New(prototype any, opts ...ParseOption) (*Validator, error) {
-	objSchema, err := Parse(reflect.TypeOf(prototype), opts...)
+	cfg := defaultParseConfig
+	if len(opts) > 0 {
+		cfg = builder.NewParseConfig(opts...)
+	}
+
+	objSchema, err := builder.ParseWithConfig(reflect.TypeOf(prototype), cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -28,19 +47,123 @@ func NewFromSchema(s schema.Schema) *Validator {
 	}
 }
 
+// WithErrorLimits returns a copy of the validator stopping validations early as set by limits,
+// such as at the first error with FailFast
+func (v *Validator) WithErrorLimits(limits schema.ErrorLimits) *Validator {
+	c := *v
+	c.limits = limits
+	return &c
+}
+
+// WithGroups returns a copy of the validator selecting rules by groups, as Validate does, in
+// validations not given groups of their own, such as those of ValidatePartial for a PATCH request
+func (v *Validator) WithGroups(groups ...string) *Validator {
+	c := *v
+	c.groups = slices.Clone(groups)
+	return &c
+}
+
+// WithInternalErrors returns a copy of the validator that, when collect is set, records errors
+// of rules other than failed checks, such as a comparison of unsupported types, among the
+// validation errors and keeps going, rather than aborting the validation with the first one
+// Either way such errors wrap a schema.InternalError
+func (v *Validator) WithInternalErrors(collect bool) *Validator {
+	c := *v
+	c.collectInternal = collect
+	return &c
+}
+
+// ValidateStruct validates a struct using its tags, compiling the schema
+// on first use and caching it by type
+func ValidateStruct(value any) error {
+	v, err := New(value)
+	if err != nil {
+		return err
+	}
+
+	return v.Validate(value)
+}
+
 // Validate validates data and returns validation result
-func (v *Validator) Validate(value any) error {
+// Rules limited to validation groups only run when one of them is among groups, or
+// schema.DefaultGroup without groups or those of WithGroups; rules without groups always run
+func (v *Validator) Validate(value any, groups ...string) error {
+	return v.validate(context.Background(), value, groups, nil)
+}
+
+// ValidateContext is like Validate, with ctx reachable by rules through schema.Context.Context
+// Validation stops with an error wrapping schema.ErrCanceled and the error of ctx once ctx is
+// canceled or its deadline passes
+func (v *Validator) ValidateContext(ctx context.Context, value any, groups ...string) error {
+	return v.validate(ctx, value, groups, nil)
+}
+
+// ValidatePartial validates only the fields at paths and everything below them, such as
+// "address.city", `labels["app.io/tier"]` or "items[*].sku", in the grammar of data.ParsePath
+// Rules of the objects holding them don't run, while cross-field rules can still read
+// the fields left out. Paths not in the schema are reported with schema.ErrUnknownPath
+// Rules are selected by the groups set with WithGroups
+func (v *Validator) ValidatePartial(value any, paths ...string) error {
+	return v.ValidatePartialContext(context.Background(), value, paths...)
+}
+
+// ValidatePartialContext is like ValidatePartial, with ctx used as in ValidateContext
+func (v *Validator) ValidatePartialContext(ctx context.Context, value any, paths ...string) error {
+	return v.validate(ctx, value, nil, func(sctx *schema.Context) error {
+		return sctx.Include(paths...)
+	})
+}
+
+// ValidateExcept validates everything but the fields at paths and below them, with groups as in ValidatePartial
+func (v *Validator) ValidateExcept(value any, paths ...string) error {
+	return v.ValidateExceptContext(context.Background(), value, paths...)
+}
+
+// ValidateExceptContext is like ValidateExcept, with ctx used as in ValidateContext
+func (v *Validator) ValidateExceptContext(ctx context.Context, value any, paths ...string) error {
+	return v.validate(ctx, value, nil, func(sctx *schema.Context) error {
+		return sctx.Exclude(paths...)
+	})
+}
+
+// validate validates data with ctx in groups, or those of WithGroups without groups, with a
+// root context prepared by setup, if any
+func (v *Validator) validate(ctx context.Context, value any, groups []string, setup func(sctx *schema.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", schema.ErrCanceled, err)
+	}
+
+	if len(groups) == 0 {
+		groups = v.groups
+	}
+
 	// Create data accessor
 	accessor := data.New(value)
 
 	// Create validation context
-	ctx := schema.NewContext(v.schema, accessor)
-	err := v.schema.Validate(ctx)
+	sctx := schema.NewContext(v.schema, accessor)
+	if v.limits != (schema.ErrorLimits{}) {
+		sctx.SetErrorLimits(v.limits)
+	}
+	sctx.SetCollectInternal(v.collectInternal)
+	sctx.SetContext(ctx)
+	sctx.SetGroups(groups...)
+	if setup != nil {
+		if err := setup(sctx); err != nil {
+			return err
+		}
+	}
+
+	err := v.schema.Validate(sctx)
 	if err != nil {
 		return err
 	}
 
-	errs := ctx.Errors()
+	if err := sctx.ResolveDeferred(); err != nil {
+		return err
+	}
+
+	errs := sctx.Errors()
 	if len(errs) == 0 {
 		return nil
 	}
@@ -1,11 +1,20 @@
 package validator
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
-	"github.com/weilence/schema-validator/schema"
+	"github.com/weilence/schema-validator/builder"
+	"github.com/weilence/schema-validator/data"
+	"github.com/weilence/schema-validator/expr"
 	"github.com/weilence/schema-validator/rule"
+	"github.com/weilence/schema-validator/schema"
 )
 
 // Test 1: Tag-based validation
@@ -360,10 +369,10 @@ func (f DynamicForm) ModifySchema(ctx *schema.Context) {
 			valueSchema := Field().
 				AddValidator("required").
 				Build()
-			ctx.Schema().(*schema.ObjectSchema).AddField("value", valueSchema)
+			ctx.Overlay().AddField("value", valueSchema)
 		} else {
 			// Make value optional (override any existing schema)
-			ctx.Schema().(*schema.ObjectSchema).RemoveField("value")
+			ctx.Overlay().RemoveField("value")
 		}
 	}
 
@@ -373,6 +382,27 @@ func (f DynamicForm) ModifySchema(ctx *schema.Context) {
 	}
 }
 
+// wrappedSchema validates through the schema it embeds, as schemas of other packages may
+type wrappedSchema struct {
+	schema.Schema
+}
+
+// Modifiers of objects validated for another schema, such as a wrapper, modify that object
+func TestSchemaModifier_Wrapped(t *testing.T) {
+	form, err := builder.Parse(reflect.TypeOf(DynamicForm{}))
+	if err != nil {
+		t.Fatalf("Failed to parse form: %v", err)
+	}
+
+	v := NewFromSchema(wrappedSchema{form})
+	if err := v.Validate(DynamicForm{Type: "text", Required: true}); err == nil || err.Error() != "value: required" {
+		t.Errorf("Expected value error, got %v", err)
+	}
+	if err := v.Validate(DynamicForm{Type: "text"}); err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	}
+}
+
 // Test SchemaModifier interface
 func TestSchemaModifier(t *testing.T) {
 	v, err := New(DynamicForm{})
@@ -430,6 +460,38 @@ func TestSchemaModifier(t *testing.T) {
 	}
 }
 
+// Test SchemaModifier changes stay scoped to a single validation run
+func TestSchemaModifierConcurrent(t *testing.T) {
+	v, err := New(DynamicForm{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			err := v.Validate(DynamicForm{Type: "text", Required: true})
+			if errs, ok := err.(schema.ValidationErrors); !ok || !errs.HasFieldError("value") {
+				t.Errorf("Expected error on value field when required=true, got %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := v.Validate(DynamicForm{Type: "text", Required: false}); err != nil {
+				t.Errorf("Expected validation to pass when required=false, got errors: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The compiled schema must not have been modified
+	if v.schema.(*schema.ObjectSchema).Field("value") == nil {
+		t.Error("Expected compiled schema to keep the value field")
+	}
+}
+
 // NestedUser implements SchemaModifier to add zip code validation based on country
 type NestedUser struct {
 	Name string `json:"name" validate:"required"`
@@ -449,7 +511,7 @@ func (u NestedUser) ModifySchema(ctx *schema.Context) {
 			AddValidator("min", 5).
 			AddValidator("max", 5).
 			Build()
-		ctx.Schema().(*schema.ObjectSchema).AddField("zipCode", zipCodeSchema)
+		ctx.Overlay().AddField("zipCode", zipCodeSchema)
 	}
 }
 
@@ -471,7 +533,7 @@ func (u NestedAddress) ModifySchema(ctx *schema.Context) {
 			AddValidator("min", 5).
 			AddValidator("max", 5).
 			Build()
-		ctx.Schema().(*schema.ObjectSchema).AddField("country", zipCodeSchema)
+		ctx.Overlay().AddField("country", zipCodeSchema)
 	}
 }
 
@@ -509,3 +571,1422 @@ func TestSchemaModifierNestedAccess(t *testing.T) {
 		t.Error("Expected validation to fail for US zip code with length < 5")
 	}
 }
+
+// Test invalid tags are reported as errors instead of panics
+func TestParseErrors(t *testing.T) {
+	type Inner struct {
+		Code string `json:"code" validate:"len=abc"`
+	}
+
+	type Payload struct {
+		Name  string   `json:"name" validate:"required|emial"`
+		Age   int      `json:"age" validate:"min=1,2"`
+		Email string   `json:"email" validate:"email=x"`
+		Tags  []string `json:"tags" validate:"max=3|dive|nope"`
+		Inner Inner    `json:"inner"`
+	}
+
+	_, err := New(Payload{})
+	if err == nil {
+		t.Fatal("Expected parse error for invalid tags")
+	}
+
+	var errs rule.BuildErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected rule.BuildErrors, got %T", err)
+	}
+
+	expected := []struct {
+		typ   reflect.Type
+		field string
+		rule  string
+		err   error
+	}{
+		{reflect.TypeFor[Payload](), "Name", "emial", rule.ErrNotFound},
+		{reflect.TypeFor[Payload](), "Age", "min", rule.ErrInvalidParams},
+		{reflect.TypeFor[Payload](), "Email", "email", rule.ErrInvalidParams},
+		{reflect.TypeFor[Payload](), "Tags", "nope", rule.ErrNotFound},
+		{reflect.TypeFor[Inner](), "Code", "len", rule.ErrInvalidParams},
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %d: %v", len(expected), len(errs), errs)
+	}
+
+	for i, e := range expected {
+		got := errs[i]
+		if got.Type != e.typ || got.Field != e.field || got.Rule != e.rule || !errors.Is(got, e.err) {
+			t.Errorf("error %d: expected %v.%s %s (%v), got %v", i, e.typ, e.field, e.rule, e.err, got)
+		}
+	}
+}
+
+// Test SchemaBuilder collects invalid validators
+func TestBuilderBuildE(t *testing.T) {
+	_, err := Field().AddValidator("required").AddValidator("nope").AddValidator("min").BuildE()
+	if err == nil {
+		t.Fatal("Expected build error for invalid validators")
+	}
+
+	var errs rule.BuildErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected rule.BuildErrors, got %T", err)
+	}
+
+	if len(errs) != 2 || !errors.Is(errs[0], rule.ErrNotFound) || !errors.Is(errs[1], rule.ErrInvalidParams) {
+		t.Errorf("unexpected build errors: %v", errs)
+	}
+
+	s, err := Field().AddValidator("required").AddValidator("min", 1).BuildE()
+	if err != nil || s == nil {
+		t.Errorf("Expected schema to build, got %v", err)
+	}
+}
+
+type TreeNode struct {
+	Name     string      `json:"name" validate:"required"`
+	Children []*TreeNode `json:"children" validate:"max=3"`
+	Parent   *TreeNode   `json:"parent"`
+}
+
+type Thread struct {
+	Title    string    `json:"title" validate:"required"`
+	Comments []Comment `json:"comments"`
+}
+
+type Comment struct {
+	Body    string  `json:"body" validate:"required"`
+	Replies *Thread `json:"replies"`
+}
+
+type LinkedItem struct {
+	*LinkedItem
+	Value string `json:"value" validate:"required"`
+}
+
+// Test recursive and self-referential types
+func TestRecursiveTypes(t *testing.T) {
+	v, err := New(TreeNode{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	tree := TreeNode{
+		Name: "root",
+		Children: []*TreeNode{
+			{
+				Name: "a",
+				Children: []*TreeNode{
+					{Name: "a1"},
+					{Name: "a2"},
+					{Name: ""},
+				},
+			},
+			{Name: "b"},
+		},
+	}
+
+	err = v.Validate(tree)
+	errs, ok := err.(schema.ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors, got %T: %v", err, err)
+	}
+	if len(errs) != 1 || !errs.HasFieldError("children[0].children[2].name") {
+		t.Errorf("Expected a single error on children[0].children[2].name, got %v", errs)
+	}
+
+	tree.Children[0].Children[2].Name = "a3"
+	tree.Children[0].Children = append(tree.Children[0].Children, &TreeNode{Name: "a4"})
+	err = v.Validate(tree)
+	errs, ok = err.(schema.ValidationErrors)
+	if !ok || len(errs) != 1 || !errs.HasFieldError("children[0].children") {
+		t.Errorf("Expected a single error on children[0].children, got %v", err)
+	}
+
+	// Mutually recursive types
+	v, err = New(Thread{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	thread := Thread{
+		Title: "t",
+		Comments: []Comment{
+			{Body: "c", Replies: &Thread{Title: "", Comments: []Comment{{Body: ""}}}},
+		},
+	}
+
+	err = v.Validate(thread)
+	errs, ok = err.(schema.ValidationErrors)
+	if !ok || len(errs) != 2 ||
+		!errs.HasFieldError("comments[0].replies.title") ||
+		!errs.HasFieldError("comments[0].replies.comments[0].body") {
+		t.Errorf("unexpected errors for mutually recursive types: %v", err)
+	}
+
+	// Struct embedding a pointer to itself
+	v, err = New(LinkedItem{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	err = v.Validate(LinkedItem{})
+	errs, ok = err.(schema.ValidationErrors)
+	if !ok || !errs.HasFieldError("value") {
+		t.Errorf("Expected error on value field, got %v", err)
+	}
+}
+
+// Test ValidateStruct compiles on first use and reuses the cached schema
+func TestValidateStruct(t *testing.T) {
+	type Login struct {
+		Email    string `json:"email" validate:"required|email"`
+		Password string `json:"password" validate:"required|min=8"`
+	}
+
+	if err := ValidateStruct(Login{Email: "test@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+
+	err := ValidateStruct(&Login{Email: "bad"})
+	errs, ok := err.(schema.ValidationErrors)
+	if !ok || !errs.HasFieldError("email") || !errs.HasFieldError("password") {
+		t.Errorf("Expected errors on email and password, got %v", err)
+	}
+
+	v1, _ := New(Login{})
+	v2, _ := New(&Login{})
+	if v1.schema != v2.schema {
+		t.Error("Expected validators without options to share the cached schema")
+	}
+}
+
+// Test rules on struct-typed and map-typed fields
+func TestObjectFieldRules(t *testing.T) {
+	type Address struct {
+		City string `json:"city" validate:"required"`
+	}
+
+	type Order struct {
+		Kind     string            `json:"kind"`
+		Shipping *Address          `json:"shipping" validate:"required"`
+		Billing  *Address          `json:"billing" validate:"omitempty"`
+		Pickup   *Address          `json:"pickup" validate:"required_if=Kind,pickup"`
+		Home     Address           `json:"home" validate:"required"`
+		Extra    *Address          `json:"extra"`
+		Labels   map[string]string `json:"labels" validate:"required"`
+		Stops    []*Address        `json:"stops" validate:"dive|required"`
+	}
+
+	v, err := New(Order{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	err = v.Validate(Order{Kind: "pickup", Stops: []*Address{nil, {City: ""}}})
+	errs, ok := err.(schema.ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors, got %T: %v", err, err)
+	}
+
+	for _, path := range []string{"shipping", "pickup", "home", "labels", "stops[0]", "stops[1].city"} {
+		if !errs.HasFieldError(path) {
+			t.Errorf("Expected error on %s, got %v", path, errs)
+		}
+	}
+	for _, path := range []string{"billing", "extra", "shipping.city"} {
+		if errs.HasFieldError(path) {
+			t.Errorf("Unexpected error on %s: %v", path, errs)
+		}
+	}
+
+	valid := Order{
+		Kind:     "delivery",
+		Shipping: &Address{City: "Paris"},
+		Home:     Address{City: "Lyon"},
+		Labels:   map[string]string{"a": "b"},
+	}
+	if err := v.Validate(valid); err != nil {
+		t.Errorf("Expected validation to pass, got %v", err)
+	}
+
+	// Rules on a field must not leak into the shared schema of the nested type
+	v, err = New(Address{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+	err = v.Validate(&Address{})
+	errs, ok = err.(schema.ValidationErrors)
+	if !ok || len(errs) != 1 || !errs.HasFieldError("city") {
+		t.Errorf("Expected a single error on city, got %v", err)
+	}
+}
+
+// Test map key and value validation
+func TestMapKeyValueValidation(t *testing.T) {
+	type Address struct {
+		City string `json:"city" validate:"required"`
+	}
+
+	type Config struct {
+		Labels    map[string]string   `json:"labels" validate:"max=3|dive|keys|alpha|max=10|endkeys|required"`
+		Addresses map[string]Address  `json:"addresses"`
+		Limits    map[string]int      `json:"limits" validate:"dive|min=1"`
+		Nested    map[string][]string `json:"nested" validate:"dive|keys|alpha|endkeys|min=1|dive|required"`
+	}
+
+	v, err := New(Config{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	cfg := Config{
+		Labels:    map[string]string{"env": "", "team1": "core", "tier": "web"},
+		Addresses: map[string]Address{"home": {City: ""}, "work": {City: "Paris"}},
+		Limits:    map[string]int{"cpu": 0, "mem": 2},
+		Nested:    map[string][]string{"ok": {"a", ""}, "empty": {}},
+	}
+
+	err = v.Validate(cfg)
+	errs, ok := err.(schema.ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors, got %T: %v", err, err)
+	}
+
+	expected := []string{
+		"labels[env]",
+		"labels[team1]." + schema.MapKeyMarker,
+		"addresses[home].city",
+		"limits[cpu]",
+		"nested[ok][1]",
+		"nested[empty]",
+	}
+	for _, path := range expected {
+		if !errs.HasFieldError(path) {
+			t.Errorf("Expected error on %s, got %v", path, errs)
+		}
+	}
+	if len(errs) != len(expected) {
+		t.Errorf("Expected %d errors, got %v", len(expected), errs)
+	}
+
+	// Unbalanced keys keyword is reported at parse time
+	type Bad struct {
+		Labels map[string]string `validate:"dive|keys|alpha"`
+	}
+	_, err = New(Bad{})
+	if !errors.Is(err, rule.ErrInvalidSyntax) {
+		t.Errorf("Expected invalid syntax error, got %v", err)
+	}
+
+	// Code-built map schema
+	mapSchema := Map(
+		Field().AddValidator("alpha").Build(),
+		Field().AddValidator("min", 1).Build(),
+	).AddValidator("required").Build()
+	s := Object().WithField("limits", mapSchema).Build()
+
+	err = NewFromSchema(s).Validate(map[string]any{"limits": map[string]int{"cpu": 0, "m3m": 1}})
+	errs, ok = err.(schema.ValidationErrors)
+	if !ok || len(errs) != 2 || !errs.HasFieldError("limits[cpu]") || !errs.HasFieldError("limits[m3m].$key") {
+		t.Errorf("unexpected errors for code-built map schema: %v", err)
+	}
+}
+
+// Test fields are validated and reported in declaration order
+func TestDeterministicFieldOrder(t *testing.T) {
+	type Audit struct {
+		CreatedBy string `json:"createdBy" validate:"required"`
+		UpdatedBy string `json:"updatedBy" validate:"required"`
+	}
+
+	type Profile struct {
+		Zip  string `json:"zip" validate:"required"`
+		Name string `json:"name" validate:"required"`
+		Audit
+		Email   string `json:"email" validate:"required|email"`
+		Age     int    `json:"age" validate:"min=18"`
+		Country string `json:"country" validate:"required"`
+	}
+
+	v, err := New(Profile{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	expectedFields := []string{"zip", "name", "createdBy", "updatedBy", "email", "age", "country"}
+	if fields := v.schema.(*schema.ObjectSchema).Fields(); !slices.Equal(fields, expectedFields) {
+		t.Errorf("Expected fields %v, got %v", expectedFields, fields)
+	}
+
+	expected := "zip: required\nname: required\ncreatedBy: required\nupdatedBy: required\nemail: required\nemail: email\nage: min [18]\ncountry: required"
+	for i := 0; i < 20; i++ {
+		err := v.Validate(Profile{})
+		if err == nil || err.Error() != expected {
+			t.Fatalf("Expected errors in declaration order:\n%s\ngot:\n%v", expected, err)
+		}
+	}
+
+	s := Object().
+		WithField("b", Field().Required().Build()).
+		WithField("a", Field().Required().Build()).
+		WithField("c", Field().Required().Build()).
+		Build()
+
+	if fields := s.(*schema.ObjectSchema).Fields(); !slices.Equal(fields, []string{"b", "a", "c"}) {
+		t.Errorf("Expected fields in builder order, got %v", fields)
+	}
+
+	for i := 0; i < 20; i++ {
+		err := NewFromSchema(s).Validate(map[string]any{"a": "", "b": "", "c": ""})
+		if err == nil || err.Error() != "b: required\na: required\nc: required" {
+			t.Fatalf("Expected errors in builder order, got %v", err)
+		}
+	}
+}
+
+func TestCombinatorRules(t *testing.T) {
+	type Theme struct {
+		Color string `json:"color" validate:"anyOf(hexcolor|rgb|rgba)"`
+		ID    string `json:"id" validate:"anyOf(uuid4|isdefault)"`
+		Name  string `json:"name" validate:"required|not(oneof=admin,root)"`
+		Code  string `json:"code" validate:"anyOf(allOf(startswith=A|len=3)|len=5)"`
+		Level int    `json:"level" validate:"min=1|anyOf(max=3|oneof=10,20)"`
+	}
+
+	v, err := New(Theme{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	if err := v.Validate(Theme{Color: "rgb(1,2,3)", Name: "ann", Code: "ABC", Level: 20}); err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	}
+
+	if err := v.Validate(Theme{Color: "#fff", ID: "3f1e8a52-6c1d-4c3a-9f55-0f2a8b1c2d3e", Name: "bob", Code: "BCDEF", Level: 2}); err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	}
+
+	err = v.Validate(Theme{Color: "blue", ID: "nope", Name: "root", Code: "ABCD", Level: 5})
+	expected := "color: anyOf\nid: anyOf\nname: not\ncode: anyOf\nlevel: anyOf"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Expected errors:\n%s\ngot:\n%v", expected, err)
+	}
+
+	// Each failed branch is listed under the single anyOf error
+	var errs schema.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected ValidationErrors, got %T", err)
+	}
+
+	var branches schema.BranchErrors
+	if !errors.As(errs[0].Err, &branches) || len(branches) != 3 {
+		t.Fatalf("Expected 3 branch errors, got %v", errs[0].Err)
+	}
+	for i, code := range []string{"hexcolor", "rgb", "rgba"} {
+		if !branches[i].HasErrorCode(code) {
+			t.Errorf("Expected branch %d to fail with %s, got %v", i, code, branches[i])
+		}
+	}
+
+	// The allOf branch of code reports both of its rules
+	if !errors.As(errs[3].Err, &branches) || !branches[0].HasErrorCode("len") || !branches[1].HasErrorCode("len") {
+		t.Errorf("Expected len failures in both branches of code, got %v", errs[3].Err)
+	}
+
+	if _, err := New(struct {
+		A string `validate:"oneOf(alpha)"`
+		B string `validate:"anyOf()"`
+		C string `validate:"anyOf(nosuchrule|alpha)"`
+	}{}); err == nil {
+		t.Error("Expected build errors for invalid groups")
+	} else {
+		var buildErrs rule.BuildErrors
+		if !errors.As(err, &buildErrs) || len(buildErrs) != 3 {
+			t.Errorf("Expected 3 build errors, got %v", err)
+		}
+	}
+}
+
+func TestCombinatorBuilder(t *testing.T) {
+	s := Object().
+		WithField("color", Field().AnyOf(
+			Field().AddValidator("hexcolor"),
+			Field().AddValidator("rgb"),
+		).Build()).
+		WithField("code", Field().AnyOf(
+			Field().AddValidator("startswith", "A").AddValidator("len", 3),
+			Field().AddValidator("len", 5),
+		).Build()).
+		WithField("name", Field().Required().Not(Field().AddValidator("oneof", []string{"admin", "root"})).Build()).
+		Build()
+
+	v := NewFromSchema(s)
+	if err := v.Validate(map[string]any{"color": "#fff", "code": "ABC", "name": "ann"}); err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	}
+
+	err := v.Validate(map[string]any{"color": "blue", "code": "ABCD", "name": "admin"})
+	if err == nil || err.Error() != "color: anyOf\ncode: anyOf\nname: not" {
+		t.Errorf("Expected combinator errors, got %v", err)
+	}
+
+	if _, err := Field().AnyOf(Field().AddValidator("nosuchrule")).BuildE(); !errors.Is(err, rule.ErrNotFound) {
+		t.Errorf("Expected branch build errors to be reported, got %v", err)
+	}
+
+	// Branches without rules always pass
+	s = Object().
+		WithField("contact", Field().AnyOf(Field(), Field().AddValidator("email")).Build()).
+		WithField("nickname", Field().Not(Field()).Build()).
+		Build()
+	err = NewFromSchema(s).Validate(map[string]any{"contact": "ann", "nickname": "a"})
+	if err == nil || err.Error() != "nickname: not" {
+		t.Errorf("Expected not error, got %v", err)
+	}
+
+	// Missing branches and branches whose structure would be lost are build errors
+	for name, b := range map[string]*SchemaBuilder{
+		"no branches":   Field().AnyOf(),
+		"object branch": Field().AnyOf(Object().WithField("a", Field().Required().Build())),
+		"array branch":  Field().Not(Array(Field().Build())),
+	} {
+		if _, err := b.BuildE(); !errors.Is(err, rule.ErrInvalidSyntax) {
+			t.Errorf("%s: expected syntax error, got %v", name, err)
+		}
+	}
+}
+
+func TestUnionSchema(t *testing.T) {
+	click := Object().
+		WithField("x", Field().Required().Build()).
+		WithField("y", Field().Required().Build()).
+		Build()
+	key := Object().
+		WithField("code", Field().Required().AddValidator("alpha").Build()).
+		Build()
+
+	s := Object().
+		WithField("events", Array(Union("type").Variant("click", click).Variant("key", key).Build()).Build()).
+		Build()
+	v := NewFromSchema(s)
+
+	err := v.Validate(map[string]any{"events": []any{
+		map[string]any{"type": "click", "x": 1, "y": 2},
+		map[string]any{"type": "key", "code": "Enter"},
+	}})
+	if err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	}
+
+	err = v.Validate(map[string]any{"events": []any{
+		map[string]any{"type": "click", "x": 1},
+		map[string]any{"type": "key", "code": "F1"},
+		map[string]any{"type": "scroll"},
+		map[string]any{"x": 1},
+	}})
+	expected := "events[0].y: required\nevents[1].code: alpha\nevents[2].type: unknown_discriminator [scroll]\nevents[3].type: unknown_discriminator []"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected errors:\n%s\ngot:\n%v", expected, err)
+	}
+
+	// A fallback takes unknown values, and nested discriminator paths are supported
+	fallback := Object().WithField("name", Field().Required().Build()).Build()
+	nested := Union("meta.kind").Variant("key", key).Fallback(fallback).Build()
+	err = NewFromSchema(nested).Validate(map[string]any{"meta": map[string]any{"kind": "other"}})
+	if err == nil || err.Error() != "name: required" {
+		t.Errorf("Expected fallback errors, got %v", err)
+	}
+
+	// Struct discriminators are read through the field names of the variants
+	type clickEvent struct {
+		Type string `json:"type"`
+		X    int    `json:"x" validate:"required"`
+	}
+	clickStruct, err := Parse(reflect.TypeFor[clickEvent]())
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	v = NewFromSchema(Union("type").Variant("click", clickStruct).Build())
+	tests := []struct {
+		value    any
+		expected string
+	}{
+		{clickEvent{Type: "click"}, "x: required"},
+		{&clickEvent{Type: "click"}, "x: required"},
+		{&clickEvent{Type: "scroll", X: 1}, "type: unknown_discriminator [scroll]"},
+		{struct{ Kind string }{"click"}, "type: unknown_discriminator []"},
+	}
+	for _, tt := range tests {
+		if err := v.Validate(tt.value); err == nil || err.Error() != tt.expected {
+			t.Errorf("Expected %s for %#v, got %v", tt.expected, tt.value, err)
+		}
+	}
+}
+
+type unionShape interface {
+	Area() float64
+}
+
+type unionCircle struct {
+	Radius float64 `json:"radius" validate:"gt=0"`
+}
+
+func (c unionCircle) Area() float64 { return c.Radius * c.Radius * 3.14 }
+
+type unionRect struct {
+	Width  float64 `json:"width" validate:"gt=0"`
+	Height float64 `json:"height" validate:"gt=0"`
+}
+
+func (r *unionRect) Area() float64 { return r.Width * r.Height }
+
+type unionSquare struct {
+	Side float64
+}
+
+func (s unionSquare) Area() float64 { return s.Side * s.Side }
+
+func TestInterfaceImplementations(t *testing.T) {
+	type Drawing struct {
+		Main   unionShape   `json:"main" validate:"required"`
+		Shapes []unionShape `json:"shapes" validate:"dive"`
+		Extra  any          `json:"extra"`
+	}
+
+	v, err := New(Drawing{},
+		WithImplementations(reflect.TypeFor[unionShape](), reflect.TypeFor[unionCircle](), reflect.TypeFor[*unionRect]()),
+		WithImplementations(reflect.TypeFor[any](), reflect.TypeFor[unionCircle]()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	valid := Drawing{
+		Main:   unionCircle{Radius: 1},
+		Shapes: []unionShape{&unionRect{Width: 1, Height: 2}, unionCircle{Radius: 2}},
+		Extra:  &unionCircle{Radius: 3},
+	}
+	if err := v.Validate(valid); err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	}
+
+	err = v.Validate(Drawing{
+		Shapes: []unionShape{&unionRect{Width: 1}, unionSquare{Side: 1}},
+		Extra:  unionCircle{},
+	})
+	expected := "main: required\nshapes[0].height: gt [0]\nshapes[1]: unknown_discriminator [github.com/weilence/schema-validator.unionSquare]\nextra.radius: gt [0]"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected errors:\n%s\ngot:\n%v", expected, err)
+	}
+
+	// Invalid implementations are reported by New
+	_, err = New(Drawing{},
+		WithImplementations(reflect.TypeFor[unionCircle](), reflect.TypeFor[unionCircle]()),
+		WithImplementations(reflect.TypeFor[unionShape](), reflect.TypeFor[string]()),
+	)
+	var buildErrs rule.BuildErrors
+	if !errors.As(err, &buildErrs) || len(buildErrs) != 2 || !errors.Is(err, builder.ErrInvalidOption) {
+		t.Errorf("Expected two option errors, got %v", err)
+	}
+}
+
+func TestConditionalSchema(t *testing.T) {
+	zip := func(rule string, param any) schema.Schema {
+		return Object().WithField("zipCode", Field().AddValidator(rule, param).Build()).Build()
+	}
+
+	s := Object().
+		WithField("country", Field().Required().Build()).
+		WithField("zipCode", Field().Required().Build()).
+		When(schema.Eq("country", "US")).Then(zip("len", 5)).Else(zip("max", 10)).
+		When(schema.In("country", "US", "CA")).Then(Object().WithField("state", Field().Required().Build()).Build()).
+		When(schema.Present("vat")).Then(Object().WithField("vat", Field().AddValidator("alphanum").Build()).Build()).
+		Build()
+	v := NewFromSchema(s)
+
+	tests := []struct {
+		name     string
+		data     map[string]any
+		expected string
+	}{
+		{"then", map[string]any{"country": "US", "zipCode": "123", "state": "NY"}, "zipCode: len [5]"},
+		{"else", map[string]any{"country": "FR", "zipCode": "12345678901"}, "zipCode: max [10]"},
+		{"membership", map[string]any{"country": "CA", "zipCode": "A1A1A1"}, "state: required"},
+		{"presence", map[string]any{"country": "FR", "zipCode": "75001", "vat": "FR-1"}, "vat: alphanum"},
+		{"valid", map[string]any{"country": "US", "zipCode": "12345", "state": "NY", "vat": nil}, ""},
+	}
+
+	for _, tt := range tests {
+		err := v.Validate(tt.data)
+		if (err == nil) != (tt.expected == "") || (err != nil && err.Error() != tt.expected) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+
+	// Branches never modify the compiled schema
+	if s.(*schema.ObjectSchema).Field("state") != nil {
+		t.Error("Expected the compiled schema to have no state field")
+	}
+}
+
+func TestConditionalStruct(t *testing.T) {
+	type Item struct {
+		Kind  string `json:"kind"`
+		Price int    `json:"price"`
+	}
+
+	type Order struct {
+		Mode  string `json:"mode"`
+		Items []Item `json:"items"`
+	}
+
+	// Conditions read struct fields by their schema names, relative to the parent or the root
+	item, err := Parse(reflect.TypeFor[Item]())
+	if err != nil {
+		t.Fatalf("Failed to parse item: %v", err)
+	}
+	item = item.Clone()
+	item.When(schema.Eq("$root.mode", "free")).Then(Object().WithField("price", Field().AddValidator("max", 0).Build()).Build())
+	item.When(schema.ConditionFunc(func(ctx *schema.Context) (bool, error) {
+		kind, err := ctx.Lookup("kind")
+		return err == nil && kind.String() == "gift", err
+	})).Then(Object().WithField("price", Field().AddValidator("max", 10).Build()).Build())
+
+	s := Object().
+		WithField("mode", Field().Build()).
+		WithField("items", Array(item).Build()).
+		FieldName("mode", "Mode").
+		FieldName("items", "Items").
+		Build()
+
+	err = NewFromSchema(s).Validate(Order{Mode: "free", Items: []Item{{Kind: "gift", Price: 20}, {Price: 0}}})
+	expected := "items[0].price: max [0]\nitems[0].price: max [10]"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected errors:\n%s\ngot:\n%v", expected, err)
+	}
+
+	err = NewFromSchema(s).Validate(Order{Mode: "paid", Items: []Item{{Kind: "book", Price: 20}}})
+	if err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	}
+}
+
+func TestConditionalTag(t *testing.T) {
+	type Address struct {
+		Country string `json:"country"`
+		ZipCode string `json:"zipCode" validate:"required|when=country,US|len=5|numeric|else|max=10"`
+		State   string `json:"state" validate:"when=country,US,CA|required|when=country,US|len=2"`
+	}
+
+	v, err := New(Address{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	tests := []struct {
+		data     Address
+		expected string
+	}{
+		{Address{Country: "US", ZipCode: "12345", State: "NY"}, ""},
+		{Address{Country: "US", ZipCode: "1234a", State: "New York"}, "zipCode: numeric\nstate: len [2]"},
+		{Address{Country: "CA", ZipCode: "A1A 1A1"}, "state: required"},
+		{Address{Country: "FR", ZipCode: "12345678901"}, "zipCode: max [10]"},
+		{Address{Country: "FR"}, "zipCode: required"},
+	}
+
+	for _, tt := range tests {
+		err := v.Validate(tt.data)
+		if (err == nil) != (tt.expected == "") || (err != nil && err.Error() != tt.expected) {
+			t.Errorf("%+v: expected %q, got %v", tt.data, tt.expected, err)
+		}
+	}
+
+	// Invalid conditionals are reported when the schema is built
+	type Invalid struct {
+		A string `validate:"when=country|required"`
+		B string `validate:"required|else|max=1"`
+	}
+
+	_, err = New(Invalid{})
+	if !errors.Is(err, rule.ErrInvalidSyntax) {
+		t.Errorf("Expected invalid syntax error, got %v", err)
+	}
+
+	var buildErrs rule.BuildErrors
+	if !errors.As(err, &buildErrs) || len(buildErrs) != 2 {
+		t.Errorf("Expected 2 build errors, got %v", err)
+	}
+
+	// So are condition paths that don't follow the path grammar
+	type InvalidPath struct {
+		A string `validate:"when=address..country,US|required"`
+		B string `validate:"when=../,US|required"`
+		C string `validate:"when=$root.items[0].kind,US|required"`
+	}
+
+	_, err = New(InvalidPath{})
+	if !errors.Is(err, rule.ErrInvalidParams) || !errors.Is(err, data.ErrInvalidPath) {
+		t.Errorf("Expected invalid path error, got %v", err)
+	}
+	if !errors.As(err, &buildErrs) || len(buildErrs) != 2 {
+		t.Errorf("Expected 2 build errors, got %v", err)
+	}
+}
+
+func TestExprRule(t *testing.T) {
+	type Item struct {
+		Price int `json:"price"`
+	}
+
+	type Booking struct {
+		Start    time.Time `json:"start"`
+		End      time.Time `json:"end" validate:"expr=start < end"`
+		MaxItems int       `json:"maxItems"`
+		Items    []Item    `json:"items" validate:"expr=len(items) <= maxItems || contains(code, \"VIP\")"`
+		Code     string    `json:"code" validate:"expr=code == '' || startsWith(code, \"B-\")|max=10"`
+	}
+
+	v, err := New(Booking{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	err = v.Validate(Booking{Start: start, End: start.Add(time.Hour), MaxItems: 1, Items: []Item{{}, {}}, Code: "B-VIP"})
+	if err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	}
+
+	err = v.Validate(Booking{Start: start, End: start, MaxItems: 1, Items: []Item{{}, {}}, Code: "X-1234567890"})
+	expected := "end: expr [start < end]\nitems: expr [len(items) <= maxItems || contains(code, \"VIP\")]\ncode: expr [code == '' || startsWith(code, \"B-\")]\ncode: max [10]"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected errors:\n%s\ngot:\n%v", expected, err)
+	}
+
+	// Expressions are parsed and type-checked when the schema is built
+	type Invalid struct {
+		A string `validate:"expr=len(A, 1) > 0"`
+		B string `validate:"expr=B +"`
+	}
+
+	_, err = New(Invalid{})
+	if !errors.Is(err, expr.ErrType) || !errors.Is(err, expr.ErrSyntax) || !errors.Is(err, rule.ErrInvalidParams) {
+		t.Errorf("Expected type and syntax errors, got %v", err)
+	}
+
+	// The builder parses expressions too
+	s := Object().
+		WithField("min", Field().Build()).
+		WithField("max", Field().AddValidator("expr", "$value >= min").Build()).
+		Build()
+	if err := NewFromSchema(s).Validate(map[string]any{"min": 2, "max": 1}); err == nil || err.Error() != "max: expr [$value >= min]" {
+		t.Errorf("Expected expr error, got %v", err)
+	}
+}
+
+func TestCrossFieldPaths(t *testing.T) {
+	type Limits struct {
+		MaxAmount int `json:"maxAmount"`
+	}
+
+	type Line struct {
+		Amount   int    `json:"amount" validate:"ltefield=$root.limits.maxAmount"`
+		Currency string `json:"currency" validate:"eqfield=../../currency"`
+		Note     string `json:"note" validate:"required_with=$root.lines[0].note"`
+	}
+
+	type Order struct {
+		Currency string            `json:"currency"`
+		Limits   Limits            `json:"limits"`
+		Labels   map[string]string `json:"labels"`
+		Lines    []Line            `json:"lines"`
+		Tier     string            `json:"tier" validate:"required_if=labels[\"app.io/tier\"],gold"`
+	}
+
+	v, err := New(Order{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	valid := Order{
+		Currency: "EUR",
+		Limits:   Limits{MaxAmount: 100},
+		Lines:    []Line{{Amount: 100, Currency: "EUR"}, {Amount: 5, Currency: "EUR"}},
+	}
+	if err := v.Validate(valid); err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	}
+
+	invalid := Order{
+		Currency: "EUR",
+		Limits:   Limits{MaxAmount: 100},
+		Labels:   map[string]string{"app.io/tier": "gold"},
+		Lines:    []Line{{Amount: 101, Currency: "EUR", Note: "first"}, {Amount: 5, Currency: "USD"}},
+	}
+	expected := "lines[0].amount: ltefield [$root.limits.maxAmount]\nlines[1].currency: eqfield [../../currency]\nlines[1].note: required_with [[$root.lines[0].note]]\ntier: required_if [labels[\"app.io/tier\"] gold]"
+	if err := v.Validate(invalid); err == nil || err.Error() != expected {
+		t.Errorf("Expected errors:\n%s\ngot:\n%v", expected, err)
+	}
+}
+
+func TestCompileRules(t *testing.T) {
+	s, err := CompileRules(map[string]string{
+		"user.email":  "required|email",
+		"items":       "max=50",
+		"items.*.qty": "required|min=1",
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to compile rules: %v", err)
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(`{"user":{"email":"ann"},"items":[{"qty":1},{"qty":2},{"qty":3},{"qty":0.5}]}`), &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	err = NewFromSchema(s).Validate(payload)
+	expected := "items[3].qty: min [1]\nuser.email: email"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected errors:\n%s\ngot:\n%v", expected, err)
+	}
+}
+
+func TestVar(t *testing.T) {
+	if err := Var("ann@example.com", "required|email"); err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	}
+
+	err := Var("nope", "required|email|max=3")
+	var errs schema.ValidationErrors
+	if !errors.As(err, &errs) || err.Error() != "email\nmax [3]" {
+		t.Errorf("Expected ValidationErrors, got %v", err)
+	}
+
+	// Compiled rule strings are cached
+	if _, ok := varCache.Load(varKey{rules: "required|email|max=3"}); !ok {
+		t.Error("Expected compiled rules to be cached")
+	}
+
+	if err := Var(42, "min=50"); !errors.As(err, &errs) || !errs.HasErrorCode("min") {
+		t.Errorf("Expected min error, got %v", err)
+	}
+
+	if err := Var("x", "nosuchrule"); !errors.Is(err, rule.ErrNotFound) {
+		t.Errorf("Expected build error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := VarWithCtx(ctx, "x", "required"); !errors.Is(err, schema.ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context error, got %v", err)
+	}
+}
+
+func TestVarField(t *testing.T) {
+	if err := VarField(10, 5, "gtfield"); err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	}
+
+	if err := VarField(5, 10, "required|gtfield"); err == nil || err.Error() != "gtfield [other]" {
+		t.Errorf("Expected gtfield error, got %v", err)
+	}
+
+	if err := VarField("a", "a", "nefield=other"); err == nil || err.Error() != "nefield [other]" {
+		t.Errorf("Expected nefield error, got %v", err)
+	}
+
+	if err := VarField(1, 2, "nosuchrule"); !errors.Is(err, rule.ErrNotFound) {
+		t.Errorf("Expected build error, got %v", err)
+	}
+}
+
+func TestPartialValidation(t *testing.T) {
+	type Address struct {
+		City string `json:"city" validate:"required"`
+		Zip  string `json:"zip" validate:"required|len=5"`
+	}
+
+	type Item struct {
+		SKU string `json:"sku" validate:"required"`
+		Qty int    `json:"qty" validate:"min=1"`
+	}
+
+	type Profile struct {
+		Name     string   `json:"name" validate:"required"`
+		Password string   `json:"password" validate:"required|min=8"`
+		Confirm  string   `json:"confirm" validate:"eqfield=Password"`
+		Address  *Address `json:"address" validate:"required"`
+		Items    []Item   `json:"items" validate:"min=1"`
+	}
+
+	v, err := New(Profile{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	value := Profile{
+		Password: "short",
+		Confirm:  "other",
+		Address:  &Address{Zip: "1"},
+		Items:    []Item{{SKU: "a"}, {Qty: 2}},
+	}
+
+	tests := []struct {
+		name     string
+		validate func() error
+		expected string
+	}{
+		{"partial", func() error { return v.ValidatePartial(value, "name", "address.city") }, "name: required\naddress.city: required"},
+		// Excluded siblings can still be read by cross-field rules
+		{"partial cross-field", func() error { return v.ValidatePartial(value, "confirm") }, "confirm: eqfield [Password]"},
+		{"partial wildcard", func() error { return v.ValidatePartial(value, "items[*].sku") }, "items[1].sku: required"},
+		{"partial index", func() error { return v.ValidatePartial(value, "items.0") }, "items[0].qty: min [1]"},
+		{"partial whole object", func() error { return v.ValidatePartial(value, "address") }, "address.city: required\naddress.zip: len [5]"},
+		{"except", func() error { return v.ValidateExcept(value, "password", "address", "items.*.qty") }, "name: required\nconfirm: eqfield [Password]\nitems[1].sku: required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validate()
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected errors:\n%s\ngot:\n%v", tt.expected, err)
+			}
+		})
+	}
+
+	if err := v.ValidatePartial(Profile{Name: "ann"}, "name"); err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	}
+
+	if err := v.ValidatePartial(value, "items..sku"); !errors.Is(err, data.ErrInvalidPath) {
+		t.Errorf("Expected invalid path error, got %v", err)
+	}
+
+	for _, path := range []string{"adress.city", "items[*].price", "name.first", "address[0]"} {
+		if err := v.ValidateExcept(value, path); !errors.Is(err, schema.ErrUnknownPath) {
+			t.Errorf("Expected unknown path error for %s, got %v", path, err)
+		}
+	}
+
+	// Quoted keys select map entries holding dots
+	type Deployment struct {
+		Labels map[string]string `json:"labels" validate:"dive|required"`
+	}
+	dv, err := New(Deployment{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+	err = dv.ValidatePartial(Deployment{Labels: map[string]string{"app.io/tier": "", "team": ""}}, `labels["app.io/tier"]`)
+	if err == nil || err.Error() != "labels[app.io/tier]: required" {
+		t.Errorf("Expected tier error, got %v", err)
+	}
+
+	// Paths of map entries in errors select the same entries again
+	labels := Deployment{Labels: map[string]string{"env": "", "a]b": "", "team": "x"}}
+	err = dv.Validate(labels)
+	var errs schema.ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Expected two errors, got %v", err)
+	}
+	for _, e := range errs {
+		partial := dv.ValidatePartial(labels, e.Path)
+		if partial == nil || partial.Error() != e.Path+": required" {
+			t.Errorf("Expected only the error at %s, got %v", e.Path, partial)
+		}
+	}
+
+	// Groups set with WithGroups and contexts apply as in Validate, such as the update group of a PATCH request
+	type Account struct {
+		ID   int    `json:"id" validate:"update:required"`
+		Name string `json:"name" validate:"update:min=2"`
+	}
+	av, err := New(Account{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	patch := Account{Name: "a"}
+	if err := av.ValidatePartial(patch, "name"); err != nil {
+		t.Errorf("Expected no errors in the default group, got %v", err)
+	}
+	if err := av.WithGroups("update").ValidatePartial(patch, "name"); err == nil || err.Error() != "name: min [2]" {
+		t.Errorf("Expected name error, got %v", err)
+	}
+	if err := av.WithGroups("update").ValidateExcept(patch, "name"); err == nil || err.Error() != "id: required" {
+		t.Errorf("Expected id error, got %v", err)
+	}
+	if err := av.WithGroups("update").Validate(patch, "default"); err != nil {
+		t.Errorf("Expected groups given to Validate to replace those of WithGroups, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := av.WithGroups("update").ValidatePartialContext(ctx, patch, "name"); !errors.Is(err, schema.ErrCanceled) {
+		t.Errorf("Expected canceled error, got %v", err)
+	}
+	if err := av.ValidateExceptContext(ctx, patch, "name"); !errors.Is(err, schema.ErrCanceled) {
+		t.Errorf("Expected canceled error, got %v", err)
+	}
+}
+
+func TestValidationGroups(t *testing.T) {
+	type Account struct {
+		ID    int    `json:"id" validate:"create:isdefault|update:required"`
+		Name  string `json:"name" validate:"required|create,update:min=2"`
+		Role  string `json:"role" validate:"required" groups:"admin"`
+		Email string `json:"email" validate:"default:required"`
+	}
+
+	v, err := New(Account{}, WithGroupInheritance("admin", "update"))
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		value    Account
+		groups   []string
+		expected string
+	}{
+		{"default group", Account{Name: "a"}, nil, "email: required"},
+		{"create", Account{ID: 1, Name: "a"}, []string{"create"}, "id: isdefault\nname: min [2]"},
+		{"update", Account{Email: "x"}, []string{"update"}, "id: required\nname: required\nname: min [2]"},
+		{"inherited", Account{Name: "ann"}, []string{"admin"}, "id: required\nrole: required"},
+		{"several groups", Account{Name: "ann"}, []string{"admin", "default"}, "id: required\nrole: required\nemail: required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(tt.value, tt.groups...)
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected errors:\n%s\ngot:\n%v", tt.expected, err)
+			}
+		})
+	}
+
+	type Spaced struct {
+		ID int `json:"id" validate:"required" groups:"update, admin"`
+	}
+	sv, err := New(Spaced{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+	for _, group := range []string{"update", "admin"} {
+		if err := sv.Validate(Spaced{}, group); err == nil || err.Error() != "id: required" {
+			t.Errorf("Expected required error for group %s, got %v", group, err)
+		}
+	}
+
+	type Empty struct {
+		ID int `json:"id" validate:"required" groups:"update,,admin"`
+	}
+	if _, err := New(Empty{}); !errors.Is(err, rule.ErrInvalidSyntax) {
+		t.Errorf("Expected syntax error for an empty group, got %v", err)
+	}
+
+	// Groups in the builder apply to the validators added after them
+	s := Object().
+		InheritGroup("admin", "update").
+		WithField("id", Field().Groups("update").Required().Groups().AddValidator("min", 1).Build()).
+		Build()
+	if err := NewFromSchema(s).Validate(map[string]any{}, "admin"); err == nil || err.Error() != "id: required\nid: min [1]" {
+		t.Errorf("Expected required and min errors, got %v", err)
+	}
+	if err := NewFromSchema(s).Validate(map[string]any{}); err == nil || err.Error() != "id: min [1]" {
+		t.Errorf("Expected min error, got %v", err)
+	}
+}
+
+func TestValidateContext(t *testing.T) {
+	type roleKey struct{}
+
+	rule.Register("admin_only", func(c context.Context, ctx *schema.Context) error {
+		if !ctx.Value().IsNilOrZero() && c.Value(roleKey{}) != "admin" {
+			return schema.ErrCheckFailed
+		}
+		return nil
+	})
+
+	type Item struct {
+		SKU string `json:"sku" validate:"required"`
+	}
+	type Order struct {
+		Discount int    `json:"discount" validate:"admin_only"`
+		Items    []Item `json:"items"`
+	}
+
+	v, err := New(Order{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	order := Order{Discount: 10, Items: []Item{{SKU: "a"}, {}}}
+	admin := context.WithValue(context.Background(), roleKey{}, "admin")
+	if err := v.ValidateContext(admin, order); err == nil || err.Error() != "items[1].sku: required" {
+		t.Errorf("Expected sku error only, got %v", err)
+	}
+	if err := v.ValidateContext(context.Background(), order); err == nil || err.Error() != "discount: admin_only\nitems[1].sku: required" {
+		t.Errorf("Expected discount and sku errors, got %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = v.ValidateContext(canceled, order)
+	if !errors.Is(err, schema.ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancellation error, got %v", err)
+	}
+
+	// Cancellation during traversal stops at the next field or element
+	canceled, cancel = context.WithCancel(context.Background())
+	rule.Register("cancel_validation", func(ctx *schema.Context) error {
+		cancel()
+		return nil
+	})
+	s := Object().
+		WithField("items", Array(Field().AddValidator("cancel_validation").Build()).Build()).
+		Build()
+	err = NewFromSchema(s).ValidateContext(canceled, map[string]any{"items": []int{1, 2}})
+	if !errors.Is(err, schema.ErrCanceled) || err.Error() != "validation canceled at items[1]: context canceled" {
+		t.Errorf("Expected cancellation at items[1], got %v", err)
+	}
+
+	deadline, stop := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer stop()
+	if err := v.ValidateContext(deadline, order); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline error, got %v", err)
+	}
+}
+
+func TestAsyncValidators(t *testing.T) {
+	var calls [][]any
+	taken := map[any]bool{"ann@example.com": true}
+	rule.RegisterAsync("email_available", rule.LoaderFunc(func(ctx context.Context, params []any, values []any) ([]bool, error) {
+		calls = append(calls, values)
+		available := make([]bool, len(values))
+		for i, v := range values {
+			available[i] = !taken[v]
+		}
+		return available, nil
+	}), time.Second)
+
+	type User struct {
+		Email string `json:"email" validate:"required|email|email_available"`
+	}
+	type Import struct {
+		Users []User `json:"users"`
+	}
+
+	v, err := New(Import{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	err = v.Validate(Import{Users: []User{{"bob@example.com"}, {"ann@example.com"}, {"invalid"}, {"ann@example.com"}}})
+	expected := "users[2].email: email\nusers[1].email: email_available\nusers[3].email: email_available"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected errors:\n%s\ngot:\n%v", expected, err)
+	}
+	if !reflect.DeepEqual(calls, [][]any{{"bob@example.com", "ann@example.com", "invalid"}}) {
+		t.Errorf("Expected a single batch of distinct emails, got %v", calls)
+	}
+
+	if err := Var("ann@example.com", "email_available"); err == nil || err.Error() != "email_available" {
+		t.Errorf("Expected email_available error, got %v", err)
+	}
+
+	// Checks inside combinators are resolved before the combinator decides its result
+	rule.RegisterAsync("sku_taken", rule.LoaderFunc(func(ctx context.Context, params []any, values []any) ([]bool, error) {
+		return make([]bool, len(values)), nil
+	}), time.Second)
+
+	type Product struct {
+		Email string `json:"email" validate:"anyOf(sku_taken|email)"`
+		SKU   string `json:"sku" validate:"not(sku_taken)"`
+	}
+
+	v, err = New(Product{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	err = v.Validate(Product{Email: "notanemail", SKU: "abc"})
+	if err == nil || err.Error() != "email: anyOf" {
+		t.Errorf("Expected anyOf error, got %v", err)
+	}
+
+	// Errors of loaders are reported with their message
+	rule.RegisterAsync("sku_exists", rule.LoaderFunc(func(ctx context.Context, params []any, values []any) ([]bool, error) {
+		return nil, errors.New("catalog unavailable")
+	}), time.Second)
+
+	err = Var("abc", "sku_exists=products")
+	var internal schema.InternalError
+	if !errors.As(err, &internal) || err.Error() != "sku_exists [products]: internal error: catalog unavailable" {
+		t.Errorf("Expected loader error, got %v", err)
+	}
+}
+
+func TestErrorLimits(t *testing.T) {
+	type Item struct {
+		SKU string `json:"sku" validate:"required|min=3"`
+		Qty int    `json:"qty" validate:"min=1"`
+	}
+	type Import struct {
+		Email string `json:"email" validate:"required|email"`
+		Items []Item `json:"items" validate:"min=5"`
+	}
+
+	v, err := New(Import{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	value := Import{Items: []Item{{SKU: "abc", Qty: 1}, {}, {SKU: "x"}}}
+	tests := []struct {
+		name     string
+		limits   schema.ErrorLimits
+		expected string
+	}{
+		{"none", schema.ErrorLimits{}, "email: required\nemail: email\nitems: min [5]\nitems[1].sku: required\nitems[1].sku: min [3]\nitems[1].qty: min [1]\nitems[2].sku: min [3]\nitems[2].qty: min [1]"},
+		{"fail fast", schema.ErrorLimits{FailFast: true}, "email: required"},
+		{"bail", schema.ErrorLimits{Bail: true}, "email: required\nitems: min [5]\nitems[1].sku: required\nitems[1].qty: min [1]\nitems[2].sku: min [3]\nitems[2].qty: min [1]"},
+		{"max errors", schema.ErrorLimits{MaxErrors: 3}, "email: required\nemail: email\nitems: min [5]\ntruncated [3]"},
+		{"max errors not reached", schema.ErrorLimits{MaxErrors: 8}, "email: required\nemail: email\nitems: min [5]\nitems[1].sku: required\nitems[1].sku: min [3]\nitems[1].qty: min [1]\nitems[2].sku: min [3]\nitems[2].qty: min [1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.WithErrorLimits(tt.limits).Validate(value)
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected errors:\n%s\ngot:\n%v", tt.expected, err)
+			}
+
+			var errs schema.ValidationErrors
+			if errors.As(err, &errs) && errs.Truncated() != (tt.limits.MaxErrors == 3) {
+				t.Errorf("Unexpected truncation of %v", err)
+			}
+		})
+	}
+
+	// Failing branches of anyOf don't count as errors
+	s := Object().
+		WithField("contact", Field().AnyOf(Field().AddValidator("email"), Field().AddValidator("e164")).Build()).
+		WithField("name", Field().Required().Build()).
+		Build()
+	err = NewFromSchema(s).WithErrorLimits(schema.ErrorLimits{FailFast: true}).Validate(map[string]any{"contact": "+14155552671"})
+	if err == nil || err.Error() != "name: required" {
+		t.Errorf("Expected name error, got %v", err)
+	}
+}
+
+func TestInternalErrors(t *testing.T) {
+	type Window struct {
+		Start time.Duration `json:"start" validate:"required"`
+		Live  bool          `json:"live" validate:"gtfield=start"`
+		Name  string        `json:"name" validate:"required"`
+	}
+
+	v, err := New(Window{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	value := Window{Live: true}
+	err = v.Validate(value)
+	var internal schema.InternalError
+	if !errors.As(err, &internal) {
+		t.Fatalf("Expected internal error, got %v", err)
+	}
+
+	err = v.WithInternalErrors(true).Validate(value)
+	var errs schema.ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("Expected 3 errors, got %v", err)
+	}
+	if errs[0].Category != schema.CategoryInput || errs[2].Category != schema.CategoryInput {
+		t.Errorf("Expected input errors, got %v", err)
+	}
+	if errs[1].Path != "live" || errs[1].Category != schema.CategoryInternal || !errors.As(errs[1], &internal) || !errs.HasInternalError() {
+		t.Errorf("Expected internal error at live, got %v", err)
+	}
+	if msg := errs[1].Error(); msg != "live: gtfield [start]: internal error: unsupported type for comparison" {
+		t.Errorf("Unexpected message %q", msg)
+	}
+
+	// Internal errors in branches of combinators are reported rather than deciding their result
+	type Bounds struct{}
+	type Range struct {
+		Bounds Bounds `json:"bounds"`
+		Min    int    `json:"min" validate:"not(gtfield=bounds)"`
+		Max    int    `json:"max" validate:"anyOf(gtfield=bounds|min=1)"`
+	}
+
+	v, err = New(Range{})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	rng := Range{Min: 1, Max: 5}
+	if err := v.Validate(rng); !errors.As(err, &internal) {
+		t.Fatalf("Expected internal error, got %v", err)
+	}
+
+	err = v.WithInternalErrors(true).Validate(rng)
+	expected := "min: gtfield [bounds]: internal error: unable to cast validator.Bounds{} of type validator.Bounds to int64\n" +
+		"max: gtfield [bounds]: internal error: unable to cast validator.Bounds{} of type validator.Bounds to int64"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected errors:\n%s\ngot:\n%v", expected, err)
+	}
+}
+
+func TestInternalErrors_Conditions(t *testing.T) {
+	broken := schema.ConditionFunc(func(*schema.Context) (bool, error) {
+		return false, errors.New("broken")
+	})
+
+	s := schema.NewObject().
+		AddField("zip", schema.NewField().AddValidator(rule.When(broken, []schema.Validator{rule.NewValidator("required")}, nil))).
+		AddField("name", schema.NewField().AddValidator(rule.NewValidator("required")))
+	s.When(broken).Then(schema.NewObject().AddField("zip", schema.NewField().AddValidator(rule.NewValidator("required"))))
+
+	var internal schema.InternalError
+	if err := NewFromSchema(s).Validate(map[string]any{}); !errors.As(err, &internal) {
+		t.Fatalf("Expected internal error, got %v", err)
+	}
+
+	err := NewFromSchema(s).WithInternalErrors(true).Validate(map[string]any{})
+	expected := "zip: when: internal error: error evaluating condition: broken\n" +
+		"name: required\n" +
+		"when: internal error: error evaluating condition: broken"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected errors:\n%s\ngot:\n%v", expected, err)
+	}
+}
+
+func TestAddFieldTwice(t *testing.T) {
+	// Unions with the same discriminator combine their variants
+	s := schema.NewObject().
+		AddField("pet", schema.NewUnion("kind").AddVariant("cat", Object().WithField("name", Field().Required().Build()).Build())).
+		AddField("pet", schema.NewUnion("kind").AddVariant("dog", Object().WithField("bark", Field().Required().Build()).Build())).
+		AddField("tags", schema.NewNot(schema.NewField().AddValidator(rule.NewValidator("required")))).
+		AddField("tags", schema.NewAnyOf(schema.NewField().AddValidator(rule.NewValidator("min", 1))))
+
+	err := NewFromSchema(s).Validate(map[string]any{"pet": map[string]any{"kind": "dog"}})
+	if err == nil || err.Error() != "pet.bark: required\ntags: anyOf" {
+		t.Errorf("Expected bark and anyOf errors, got %v", err)
+	}
+	if err := NewFromSchema(s).Validate(map[string]any{"pet": map[string]any{"kind": "cat", "name": "Tom"}, "tags": []string{"a"}}); err == nil || err.Error() != "tags: not" {
+		t.Errorf("Expected not error, got %v", err)
+	}
+
+	// Schemas of different kinds are replaced
+	s = schema.NewObject().
+		AddField("a", schema.NewField().AddValidator(rule.NewValidator("required"))).
+		AddField("a", Object().WithField("b", Field().Required().Build()).Build())
+	err = NewFromSchema(s).Validate(map[string]any{"a": map[string]any{}})
+	if err == nil || err.Error() != "a.b: required" {
+		t.Errorf("Expected b error, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/weilence/schema-validator/builder"
+	"github.com/weilence/schema-validator/data"
+	"github.com/weilence/schema-validator/schema"
+)
+
+// VarOtherField is the field name under which VarField exposes the other value to its rules
+const VarOtherField = "other"
+
+// varKey identifies a compiled rule string, rules of VarField having the other field appended
+type varKey struct {
+	rules string
+	field bool
+}
+
+// varCache holds the rule strings compiled by Var and VarField with the default parse config
+var varCache sync.Map // varKey -> *schema.FieldSchema
+
+// Var validates a single value against a rule string such as "required|email"
+// The rules are compiled with the default registry on first use and cached
+func Var(value any, rules string) error {
+	return VarWithCtx(context.Background(), value, rules)
+}
+
+// VarWithCtx is like Var, with ctx reachable by rules through schema.Context.Context
+// It returns an error wrapping schema.ErrCanceled and the error of ctx if ctx is done before validation
+func VarWithCtx(ctx context.Context, value any, rules string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", schema.ErrCanceled, err)
+	}
+
+	s, err := compileVar(varKey{rules: rules})
+	if err != nil {
+		return err
+	}
+
+	sctx := schema.NewContext(s, data.New(value))
+	sctx.SetContext(ctx)
+	return validateVar(s, sctx)
+}
+
+// VarField validates value against rules comparing it to otherValue, such as "gtfield"
+// Rules written without params, other than those taking none like required, compare to
+// otherValue; others such as "required|nefield=other" can name it as VarOtherField
+func VarField(value, otherValue any, rules string) error {
+	s, err := compileVar(varKey{rules: rules, field: true})
+	if err != nil {
+		return err
+	}
+
+	parent := schema.NewContext(schema.NewObject(), data.New(map[string]any{VarOtherField: otherValue}))
+	return validateVar(s, parent.WithChild("", s, data.New(value)))
+}
+
+func compileVar(key varKey) (*schema.FieldSchema, error) {
+	if s, ok := varCache.Load(key); ok {
+		return s.(*schema.FieldSchema), nil
+	}
+
+	cfg := defaultParseConfig
+	rules := cfg.ParseTag(key.rules)
+	if key.field {
+		for i, r := range rules {
+			if len(r.Params) > 0 || len(r.Children) > 0 {
+				continue
+			}
+
+			// Unknown rules are left for NewValidators to report
+			if paramTypes, err := cfg.Registry.GetValidatorParamTypesE(r.Name); err == nil && len(paramTypes) > 0 {
+				rules[i].Params = []string{VarOtherField}
+			}
+		}
+	}
+
+	validators, err := builder.NewValidators(rules, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := schema.NewField()
+	for _, v := range validators {
+		s.AddValidator(v)
+	}
+
+	actual, _ := varCache.LoadOrStore(key, s)
+	return actual.(*schema.FieldSchema), nil
+}
+
+func validateVar(s *schema.FieldSchema, ctx *schema.Context) error {
+	if err := s.Validate(ctx); err != nil {
+		return err
+	}
+
+	if err := ctx.ResolveDeferred(); err != nil {
+		return err
+	}
+
+	if errs := ctx.Errors(); len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}